@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/audit"
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// ResponseError represent the reseponse error struct
+type ResponseError struct {
+	Message string `json:"message"`
+}
+
+// AuditHandler  represent the httphandler for audit
+type AuditHandler struct {
+	AUsecase audit.Usecase
+}
+
+// NewAuditHandler will initialize the audit resources endpoint
+func NewAuditHandler(e *echo.Echo, us audit.Usecase) {
+	handler := &AuditHandler{
+		AUsecase: us,
+	}
+	e.GET("/merchant/:id/history", handler.FetchMerchantHistory)
+	e.GET("/merchant/admin/audit", handler.Fetch)
+}
+
+// FetchMerchantHistory will fetch the audit entries of one merchant
+func (a *AuditHandler) FetchMerchantHistory(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	filter.EntityType = models.EntityMerchant
+	filter.EntityID = id
+
+	return a.fetch(c, filter)
+}
+
+// Fetch will fetch the audit entries matching the given filters
+func (a *AuditHandler) Fetch(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	filter.Actor = c.QueryParam("actor")
+	filter.EntityType = c.QueryParam("entity_type")
+	if s := c.QueryParam("entity_id"); len(s) != 0 {
+		if filter.EntityID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+		}
+	}
+
+	return a.fetch(c, filter)
+}
+
+func (a *AuditHandler) fetch(c echo.Context, filter *models.AuditFilter) error {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, count, err := a.AUsecase.Fetch(ctx, filter)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+		"total":  count,
+	})
+}
+
+// parseFilter reads the time range and pagination shared by every audit query
+func parseFilter(c echo.Context) (*models.AuditFilter, error) {
+	filter := new(models.AuditFilter)
+	var err error
+	if s := c.QueryParam("from"); len(s) != 0 {
+		if filter.From, err = parseTime(s); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if s := c.QueryParam("to"); len(s) != 0 {
+		if filter.To, err = parseTime(s); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if s := c.QueryParam("page"); len(s) != 0 {
+		if filter.Page, err = strconv.Atoi(s); err != nil || filter.Page < 1 {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if s := c.QueryParam("offset"); len(s) != 0 {
+		if filter.Offset, err = strconv.Atoi(s); err != nil || filter.Offset < 1 {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	return filter, nil
+}
+
+// parseTime accepts either an RFC3339 timestamp or unix seconds
+func parseTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func getStatusCode(c echo.Context, err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	logging.LoggerFromContext(c.Request().Context()).Error(err)
+	switch err {
+	case models.ErrInternalServerError:
+		return http.StatusInternalServerError
+	case models.ErrNotFound:
+		return http.StatusNotFound
+	case models.ErrBadParamInput:
+		return http.StatusBadRequest
+	case models.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case models.ErrForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,13 @@
+package audit
+
+import (
+	"context"
+
+	models "merchant-service/models"
+)
+
+// Repository represent the audit's repository contract
+type Repository interface {
+	Fetch(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, int64, error)
+	Store(ctx context.Context, e *models.AuditEntry) error
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"merchant-service/audit"
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+type mysqlAuditRepository struct {
+	DB *sql.DB
+}
+
+// NewMysqlAuditRepository will create an object that represent the audit.Repository interface
+func NewMysqlAuditRepository(db *sql.DB) audit.Repository {
+	return &mysqlAuditRepository{
+		DB: db,
+	}
+}
+
+func buildAuditClause(filter *models.AuditFilter) (string, []interface{}) {
+	clauses := []string{}
+	args := []interface{}{}
+	if len(filter.Actor) != 0 {
+		clauses = append(clauses, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if len(filter.EntityType) != 0 {
+		clauses = append(clauses, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		clauses = append(clauses, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if !filter.From.IsZero() {
+		clauses = append(clauses, "created_at >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		clauses = append(clauses, "created_at < ?")
+		args = append(args, filter.To)
+	}
+	if len(clauses) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+func (a *mysqlAuditRepository) Fetch(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, int64, error) {
+	clause, args := buildAuditClause(filter)
+
+	var count int64
+	err := a.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log"+clause, args...).Scan(&count)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, 0, err
+	}
+
+	query := "SELECT id, actor, action, entity_type, entity_id, changes, created_at FROM audit_log" + clause + " ORDER BY id DESC"
+	if filter.Page > 0 && filter.Offset > 0 {
+		query += " LIMIT ?, ?"
+		args = append(args, (filter.Page-1)*filter.Offset, filter.Offset)
+	}
+
+	rows, err := a.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, 0, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.AuditEntry, 0)
+
+	for rows.Next() {
+		t := new(models.AuditEntry)
+		var changes []byte
+		err = rows.Scan(
+			&t.ID,
+			&t.Actor,
+			&t.Action,
+			&t.EntityType,
+			&t.EntityID,
+			&changes,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, 0, err
+		}
+		if err = json.Unmarshal(changes, &t.Changes); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, 0, err
+		}
+		results = append(results, t)
+	}
+
+	return results, count, nil
+}
+
+func (a *mysqlAuditRepository) Store(ctx context.Context, e *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor, action, entity_type, entity_id, changes, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	res, err := a.DB.ExecContext(ctx, query, e.Actor, e.Action, e.EntityType, e.EntityID, changes, e.CreatedAt)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
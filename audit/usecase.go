@@ -0,0 +1,19 @@
+package audit
+
+import (
+	"context"
+
+	models "merchant-service/models"
+)
+
+// Usecase represent the audit's usecases
+type Usecase interface {
+	Fetch(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, int64, error)
+	Diff(before interface{}, after interface{}) ([]*models.FieldChange, error)
+	Record(ctx context.Context, action string, entityType string, entityID int64, before interface{}, after interface{}) error
+}
+
+// Owners tells whether a subject owns a merchant, so owners may read the history of their merchants
+type Owners interface {
+	IsOwner(ctx context.Context, id int64, subject string) (bool, error)
+}
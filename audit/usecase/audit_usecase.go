@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"merchant-service/audit"
+	"merchant-service/models"
+)
+
+// ignoredFields are bookkeeping columns that change on every write and carry no audit value
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+type auditUsecase struct {
+	auditRepo      audit.Repository
+	owners         audit.Owners
+	contextTimeout time.Duration
+}
+
+// NewAuditUsecase will create new an auditUsecase object representation of audit.Usecase interface
+func NewAuditUsecase(a audit.Repository, owners audit.Owners, timeout time.Duration) audit.Usecase {
+	return &auditUsecase{
+		auditRepo:      a,
+		owners:         owners,
+		contextTimeout: timeout,
+	}
+}
+
+// toFields flattens an entity into its JSON fields so entities of any type can be compared
+func toFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if v == nil {
+		return fields, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return fields, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// diff returns the field-level changes between two snapshots of the same entity, either may be nil
+func diff(before interface{}, after interface{}) ([]*models.FieldChange, error) {
+	b, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]*models.FieldChange, 0)
+	for _, k := range keys {
+		if ignoredFields[k] || reflect.DeepEqual(b[k], a[k]) {
+			continue
+		}
+		changes = append(changes, &models.FieldChange{Field: k, Before: b[k], After: a[k]})
+	}
+	return changes, nil
+}
+
+func (a *auditUsecase) Diff(before interface{}, after interface{}) ([]*models.FieldChange, error) {
+	return diff(before, after)
+}
+
+func (a *auditUsecase) Record(c context.Context, action string, entityType string, entityID int64, before interface{}, after interface{}) error {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	return a.auditRepo.Store(ctx, &models.AuditEntry{
+		Actor:      models.ActorFromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+	})
+}
+
+// authorize lets editors read the history of any merchant and owners the history of their own; the
+// whole trail, which names every actor, is for admins
+func (a *auditUsecase) authorize(ctx context.Context, filter *models.AuditFilter) error {
+	p := models.PrincipalFromContext(ctx)
+	if p == nil {
+		return models.ErrUnauthorized
+	}
+	if p.HasRole(models.RoleAdmin) {
+		return nil
+	}
+	if filter.EntityType != models.EntityMerchant || filter.EntityID == 0 || len(filter.Actor) != 0 {
+		return models.ErrForbidden
+	}
+	if p.HasRole(models.RoleEditor) {
+		return nil
+	}
+	if !p.HasRole(models.RoleOwner) || p.Kind != models.PrincipalUser {
+		return models.ErrForbidden
+	}
+	owned, err := a.owners.IsOwner(ctx, filter.EntityID, p.Subject)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+func (a *auditUsecase) Fetch(c context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, int64, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.authorize(ctx, filter); err != nil {
+		return nil, 0, err
+	}
+
+	return a.auditRepo.Fetch(ctx, filter)
+}
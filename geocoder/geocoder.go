@@ -0,0 +1,14 @@
+package geocoder
+
+import (
+	"context"
+
+	models "merchant-service/models"
+)
+
+// Geocoder represent the geocoding provider's contract. Both methods return models.ErrNotFound
+// when the provider has no match.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (*models.GeocodeResult, error)
+	Reverse(ctx context.Context, lat float64, lng float64) (*models.GeocodeResult, error)
+}
@@ -0,0 +1,129 @@
+package nominatim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"merchant-service/geocoder"
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// minInterval keeps to the one request per second asked of public Nominatim clients
+const minInterval = time.Second
+
+type nominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// place is the part of a Nominatim search or reverse result this service reads
+type place struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+	Error       string `json:"error"`
+}
+
+// NewNominatimGeocoder will create a geocoder backed by the Nominatim API at baseURL
+func NewNominatimGeocoder(baseURL string, userAgent string, timeout time.Duration) geocoder.Geocoder {
+	return &nominatimGeocoder{
+		baseURL:   baseURL,
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// wait spaces requests at least minInterval apart
+func (g *nominatimGeocoder) wait(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if d := minInterval - time.Since(g.last); d > 0 {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	g.last = time.Now()
+	return nil
+}
+
+func (g *nominatimGeocoder) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	if err := g.wait(ctx); err != nil {
+		return err
+	}
+	params.Set("format", "json")
+	req, err := http.NewRequest(http.MethodGet, g.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim: unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func toResult(p *place) (*models.GeocodeResult, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return nil, err
+	}
+	lng, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &models.GeocodeResult{Address: p.DisplayName, Latitude: lat, Longitude: lng}, nil
+}
+
+func (g *nominatimGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	var places []place
+	params := url.Values{"q": {address}, "limit": {"1"}}
+	if err := g.get(ctx, "/search", params, &places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, models.ErrNotFound
+	}
+	return toResult(&places[0])
+}
+
+func (g *nominatimGeocoder) Reverse(ctx context.Context, lat float64, lng float64) (*models.GeocodeResult, error) {
+	var p place
+	params := url.Values{
+		"lat": {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon": {strconv.FormatFloat(lng, 'f', -1, 64)},
+	}
+	if err := g.get(ctx, "/reverse", params, &p); err != nil {
+		return nil, err
+	}
+	// Nominatim answers a point with nothing near it by 200 and an error field
+	if len(p.Error) != 0 {
+		return nil, models.ErrNotFound
+	}
+	return toResult(&p)
+}
@@ -0,0 +1,67 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"merchant-service/geocoder"
+	"merchant-service/models"
+)
+
+// maxReverseMeters is how far a point may be from a known address and still resolve to it
+const maxReverseMeters = 200.0
+
+type offlineGeocoder struct {
+	byAddress map[string]*models.GeocodeResult
+	entries   []*models.GeocodeResult
+}
+
+// NewFileGeocoder will create a geocoder answering from a JSON file holding an array of
+// {"address", "latitude", "longitude"} objects
+func NewFileGeocoder(path string) (geocoder.Geocoder, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*models.GeocodeResult, 0)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	byAddress := make(map[string]*models.GeocodeResult, len(entries))
+	for _, e := range entries {
+		byAddress[normalizeAddress(e.Address)] = e
+	}
+	return &offlineGeocoder{byAddress: byAddress, entries: entries}, nil
+}
+
+// normalizeAddress makes lookups ignore case, spacing and trailing punctuation
+func normalizeAddress(address string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(strings.ToLower(address)), " "), ".,;")
+}
+
+func (g *offlineGeocoder) Geocode(ctx context.Context, address string) (*models.GeocodeResult, error) {
+	e, ok := g.byAddress[normalizeAddress(address)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	res := *e
+	return &res, nil
+}
+
+func (g *offlineGeocoder) Reverse(ctx context.Context, lat float64, lng float64) (*models.GeocodeResult, error) {
+	var nearest *models.GeocodeResult
+	best := maxReverseMeters
+	for _, e := range g.entries {
+		if d := models.Distance(lat, lng, e.Latitude, e.Longitude); d <= best {
+			nearest, best = e, d
+		}
+	}
+	if nearest == nil {
+		return nil, models.ErrNotFound
+	}
+	res := *nearest
+	return &res, nil
+}
@@ -0,0 +1,117 @@
+package offline
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"merchant-service/models"
+)
+
+const testEntries = `[
+	{"address": "1 Trang Tien, Hoan Kiem, Ha Noi", "latitude": 21.0245, "longitude": 105.8555},
+	{"address": "2 Le Loi, District 1, Ho Chi Minh City", "latitude": 10.7743, "longitude": 106.7010}
+]`
+
+func newTestGeocoder(t *testing.T, content string) (*offlineGeocoder, error) {
+	path := filepath.Join(t.TempDir(), "geocode.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewFileGeocoder(path)
+	if err != nil {
+		return nil, err
+	}
+	return g.(*offlineGeocoder), nil
+}
+
+func TestNewFileGeocoder(t *testing.T) {
+	if _, err := NewFileGeocoder(filepath.Join(t.TempDir(), "does-not-exist.json")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+	if _, err := newTestGeocoder(t, `{"address": "not an array"}`); err == nil {
+		t.Fatal("expected an error for a file that is not an array")
+	}
+}
+
+func TestGeocode(t *testing.T) {
+	g, err := newTestGeocoder(t, testEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		lat     float64
+		found   bool
+	}{
+		{"exact", "1 Trang Tien, Hoan Kiem, Ha Noi", 21.0245, true},
+		{"case and spacing", "  1 TRANG TIEN,   hoan kiem, ha noi ", 21.0245, true},
+		{"trailing punctuation", "2 Le Loi, District 1, Ho Chi Minh City.", 10.7743, true},
+		{"unknown", "3 Nowhere Street", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := g.Geocode(context.Background(), tt.address)
+			if !tt.found {
+				if err != models.ErrNotFound {
+					t.Fatalf("expected ErrNotFound, got %v, %v", res, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Latitude != tt.lat {
+				t.Fatalf("expected latitude %v, got %+v", tt.lat, res)
+			}
+		})
+	}
+
+	// callers get a copy, so changing a result does not change the next answer
+	res, _ := g.Geocode(context.Background(), "1 Trang Tien, Hoan Kiem, Ha Noi")
+	res.Latitude = 0
+	if again, _ := g.Geocode(context.Background(), "1 Trang Tien, Hoan Kiem, Ha Noi"); again.Latitude != 21.0245 {
+		t.Fatalf("a caller changed the stored entry: %+v", again)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	g, err := newTestGeocoder(t, testEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		address  string
+	}{
+		{"on the entry", 21.0245, 105.8555, "1 Trang Tien, Hoan Kiem, Ha Noi"},
+		{"about 100 meters away", 21.0254, 105.8555, "1 Trang Tien, Hoan Kiem, Ha Noi"},
+		{"past the reverse radius", 21.0300, 105.8555, ""},
+		{"nowhere near", 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := g.Reverse(context.Background(), tt.lat, tt.lng)
+			if len(tt.address) == 0 {
+				if err != models.ErrNotFound {
+					t.Fatalf("expected ErrNotFound, got %v, %v", res, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Address != tt.address {
+				t.Fatalf("expected %q, got %+v", tt.address, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	models "merchant-service/models"
+)
+
+// Repository represent the idempotency key's repository contract
+type Repository interface {
+	// Reserve claims r.Key for a new request. When the key is already held by a record younger
+	// than ttl, that record is returned and nothing is stored.
+	Reserve(ctx context.Context, r *models.IdempotencyRecord, ttl time.Duration) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, r *models.IdempotencyRecord) error
+	Delete(ctx context.Context, key string) error
+}
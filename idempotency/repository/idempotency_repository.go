@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"merchant-service/idempotency"
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation
+const mysqlDuplicateEntry = 1062
+
+type mysqlIdempotencyRepository struct {
+	DB *sql.DB
+}
+
+// NewMysqlIdempotencyRepository will create an object that represent the idempotency.Repository interface
+func NewMysqlIdempotencyRepository(db *sql.DB) idempotency.Repository {
+	return &mysqlIdempotencyRepository{
+		DB: db,
+	}
+}
+
+func (a *mysqlIdempotencyRepository) Reserve(ctx context.Context, r *models.IdempotencyRecord, ttl time.Duration) (*models.IdempotencyRecord, error) {
+	r.CreatedAt = time.Now()
+	_, err := a.DB.ExecContext(ctx, `DELETE FROM idempotency_key WHERE idem_key = ? AND created_at < ?`, r.Key, r.CreatedAt.Add(-ttl))
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	_, err = a.DB.ExecContext(ctx, `INSERT INTO idempotency_key (idem_key, fingerprint, status_code, content_type, body, created_at) VALUES (?, ?, 0, '', NULL, ?)`,
+		r.Key, r.Fingerprint, r.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if myErr, ok := err.(*mysql.MySQLError); !ok || myErr.Number != mysqlDuplicateEntry {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	existing := new(models.IdempotencyRecord)
+	err = a.DB.QueryRowContext(ctx, `SELECT idem_key, fingerprint, status_code, content_type, body, created_at FROM idempotency_key WHERE idem_key = ?`, r.Key).Scan(
+		&existing.Key,
+		&existing.Fingerprint,
+		&existing.StatusCode,
+		&existing.ContentType,
+		&existing.Body,
+		&existing.CreatedAt,
+	)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (a *mysqlIdempotencyRepository) Complete(ctx context.Context, r *models.IdempotencyRecord) error {
+	_, err := a.DB.ExecContext(ctx, `UPDATE idempotency_key SET status_code = ?, content_type = ?, body = ? WHERE idem_key = ?`,
+		r.StatusCode, r.ContentType, r.Body, r.Key)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+	}
+	return err
+}
+
+func (a *mysqlIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	_, err := a.DB.ExecContext(ctx, `DELETE FROM idempotency_key WHERE idem_key = ?`, key)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+	}
+	return err
+}
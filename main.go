@@ -5,21 +5,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	_auditHttpDelivery "merchant-service/audit/delivery/http"
+	_auditRepo "merchant-service/audit/repository"
+	_auditUsecase "merchant-service/audit/usecase"
+	"merchant-service/geocoder"
+	_nominatimGeocoder "merchant-service/geocoder/nominatim"
+	_offlineGeocoder "merchant-service/geocoder/offline"
+	_idempotencyRepo "merchant-service/idempotency/repository"
 	_httpDelivery "merchant-service/merchant/delivery/http"
 	_merchantRepo "merchant-service/merchant/repository"
 	_merchantUsecase "merchant-service/merchant/usecase"
+	"merchant-service/metrics"
 	middleware "merchant-service/middleware"
+	"merchant-service/models"
+	"merchant-service/notifier"
+	_logNotifier "merchant-service/notifier/stub"
+	_webhookNotifier "merchant-service/notifier/webhook"
+	_memoryLimiter "merchant-service/ratelimit/memory"
+	"merchant-service/tracing"
 )
 
 func init() {
@@ -29,8 +49,10 @@ func init() {
 		panic(err)
 	}
 
+	logrus.SetFormatter(&logrus.JSONFormatter{})
 	if viper.GetBool(`debug`) {
-		fmt.Println("Service run on DEBUG mode")
+		logrus.SetLevel(logrus.DebugLevel)
+		logrus.Info("Service run on DEBUG mode")
 	}
 }
 
@@ -40,38 +62,188 @@ func main() {
 	dbUser := viper.GetString(`database.user`)
 	dbPass := viper.GetString(`database.pass`)
 	dbName := viper.GetString(`database.name`)
-	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, dbPort, dbName)
+	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", dbUser, dbPass, dbHost, dbPort, dbName)
 	dsn := fmt.Sprintf("%s", connection)
 	dbConn, err := sql.Open(`mysql`, dsn)
-	if err != nil && viper.GetBool("debug") {
-		fmt.Println(err)
+	if err != nil {
+		logrus.Fatal(err)
 	}
 	err = dbConn.Ping()
 	if err != nil {
-		log.Fatal(err)
-		os.Exit(1)
+		logrus.Fatal(err)
 	}
-	fmt.Println("Connected...")
+	logrus.Info("Connected...")
 
 	defer func() {
 		err := dbConn.Close()
 		if err != nil {
-			log.Fatal(err)
+			logrus.Fatal(err)
+		}
+	}()
+
+	var geo geocoder.Geocoder
+	switch viper.GetString("geocoder.provider") {
+	case "file":
+		geo, err = _offlineGeocoder.NewFileGeocoder(viper.GetString("geocoder.file"))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	default:
+		geo = _nominatimGeocoder.NewNominatimGeocoder(viper.GetString("geocoder.url"), viper.GetString("geocoder.user_agent"),
+			time.Duration(viper.GetInt("context.timeout"))*time.Second)
+	}
+
+	var tracingConfig tracing.Config
+	if err := viper.UnmarshalKey("tracing", &tracingConfig); err != nil {
+		logrus.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Init("merchant-service", tracingConfig)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.Error(err)
 		}
 	}()
 
 	e := echo.New()
 	middL := middleware.InitMiddleware()
-	e.Use(middL.CORS)
+	e.Use(middL.Tracing())
+	e.Use(middL.RequestLog(logrus.StandardLogger()))
+	e.Use(middL.Metrics(metrics.NewHTTP(prometheus.DefaultRegisterer)))
+	var corsConfig middleware.CORSConfig
+	if err := viper.UnmarshalKey("cors", &corsConfig); err != nil {
+		logrus.Fatal(err)
+	}
+	cors, err := middL.CORS(corsConfig)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	e.Use(cors)
+	authConfig := middleware.AuthConfig{
+		HS256Secret: viper.GetString("auth.jwt.hs256_secret"),
+		Issuer:      viper.GetString("auth.jwt.issuer"),
+		Audience:    viper.GetString("auth.jwt.audience"),
+	}
+	if err := viper.UnmarshalKey("auth.api_keys", &authConfig.APIKeys); err != nil {
+		logrus.Fatal(err)
+	}
+	if path := viper.GetString("auth.jwt.rs256_public_key_file"); len(path) != 0 {
+		if authConfig.RS256PublicKey, err = ioutil.ReadFile(path); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+	authenticator, err := middleware.NewAuthenticator(authConfig)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	e.Use(middL.Auth(authenticator))
+	var rateLimitConfig middleware.RateLimitConfig
+	if err := viper.UnmarshalKey("rate_limit", &rateLimitConfig); err != nil {
+		logrus.Fatal(err)
+	}
+	rateLimit, err := middL.RateLimit(_memoryLimiter.NewMemoryLimiter(viper.GetInt("rate_limit.max_buckets")), rateLimitConfig)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	e.Use(rateLimit)
+	idempotencyTTL := time.Duration(viper.GetInt("idempotency.ttl")) * time.Second
+	e.Use(middL.Idempotency(_idempotencyRepo.NewMysqlIdempotencyRepository(dbConn), idempotencyTTL))
+	var httpCacheConfig middleware.HTTPCacheConfig
+	if err := viper.UnmarshalKey("http_cache", &httpCacheConfig); err != nil {
+		logrus.Fatal(err)
+	}
+	e.Use(middL.ConditionalGet(httpCacheConfig))
 	e.GET("/", func(c echo.Context) error {
 		return c.String(http.StatusOK, "Hi! I am a merchant-service")
 	})
+	metrics.RegisterDBStats(prometheus.DefaultRegisterer, dbConn)
+	// the metrics are for the scraper only, so they are served on a port of their own that is not
+	// exposed with the API
+	metricsServer := &http.Server{Addr: viper.GetString("metrics.address"), Handler: promhttp.Handler()}
 
 	// Routes
-	articleRepo := _merchantRepo.NewMysqlMerchantRepository(dbConn)
 	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
-	articleUsecase := _merchantUsecase.NewMerchantUsecase(articleRepo, timeoutContext)
+	cacheTTL := _merchantRepo.CacheTTL{
+		Categories: time.Duration(viper.GetInt("cache.ttl.categories")) * time.Second,
+		Areas:      time.Duration(viper.GetInt("cache.ttl.areas")) * time.Second,
+		Merchant:   time.Duration(viper.GetInt("cache.ttl.merchant")) * time.Second,
+	}
+	articleRepo := _merchantRepo.NewInstrumentedMerchantRepository(_merchantRepo.NewMysqlMerchantRepository(dbConn), metrics.NewRepository(prometheus.DefaultRegisterer))
+	articleRepo = _merchantRepo.NewCachedMerchantRepository(articleRepo, cacheTTL, viper.GetInt("cache.size"), metrics.NewCache(prometheus.DefaultRegisterer))
+	auditRepo := _auditRepo.NewMysqlAuditRepository(dbConn)
+	// owners may read the history of their merchants
+	auditUsecase := _auditUsecase.NewAuditUsecase(auditRepo, articleRepo, timeoutContext)
+	_auditHttpDelivery.NewAuditHandler(e, auditUsecase)
+
+	var sms notifier.Notifier
+	switch viper.GetString("notifier.provider") {
+	case "webhook":
+		sms = _webhookNotifier.NewWebhookNotifier(viper.GetString("notifier.url"), viper.GetString("notifier.token"), timeoutContext)
+	default:
+		// the stub delivers nothing, so claims could never be verified outside development
+		if !viper.GetBool("debug") {
+			logrus.Fatal("notifier.provider must name a real SMS gateway unless debug is on")
+		}
+		sms = _logNotifier.NewLogNotifier()
+	}
+	articleUsecase := _merchantUsecase.NewTracedMerchantUsecase(
+		_merchantUsecase.NewMerchantUsecase(articleRepo, auditUsecase, geo, sms, timeoutContext))
+
+	// jobs started by the service itself act as an admin service principal
+	systemCtx := models.ContextWithPrincipal(context.Background(), &models.Principal{
+		Subject: "merchant-service",
+		Kind:    models.PrincipalService,
+		Role:    models.RoleAdmin,
+	})
+
+	// "go run main.go geocode-backfill" fills missing coordinates and exits instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "geocode-backfill" {
+		count, err := articleUsecase.BackfillCoordinates(systemCtx)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("geocoded %d merchants", count)
+		return
+	}
 	_httpDelivery.NewMerchantHandler(e, articleUsecase)
+	_httpDelivery.NewSEOHandler(e, articleUsecase, viper.GetString("seo.base_url"))
+
+	if interval := viper.GetInt("duplicates.interval"); interval > 0 {
+		go func() {
+			for range time.Tick(time.Duration(interval) * time.Second) {
+				count, err := articleUsecase.DetectDuplicates(systemCtx)
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				logrus.Infof("duplicate detection found %d candidates", count)
+			}
+		}()
+	}
 
-	e.Logger.Fatal(e.Start(":1080"))
+	go func() {
+		if err := e.Start(":1080"); err != nil && err != http.ErrServerClosed {
+			logrus.Fatal(err)
+		}
+	}()
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logrus.Fatal(err)
+		}
+	}()
+
+	// stop on a signal rather than being killed, so the deferred cleanups flush the buffered spans
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		logrus.Error(err)
+	}
+	if err := metricsServer.Shutdown(ctx); err != nil {
+		logrus.Error(err)
+	}
 }
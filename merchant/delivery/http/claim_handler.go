@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/models"
+)
+
+// verifyRequest represent the request body confirming a claim
+type verifyRequest struct {
+	Code string `json:"code"`
+}
+
+// RequestClaim will open a claim on a merchant and send a verification code to its phone
+func (a *MerchantHandler) RequestClaim(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	claim, err := a.MUsecase.RequestClaim(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{
+		"status": 1,
+		"data":   claim,
+	})
+}
+
+// VerifyClaim will confirm a claim with the code sent to the merchant's phone
+func (a *MerchantHandler) VerifyClaim(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var req verifyRequest
+	if err := c.Bind(&req); err != nil || len(req.Code) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	claim, err := a.MUsecase.VerifyClaim(ctx, int64(idP), req.Code)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   claim,
+	})
+}
+
+// DisputeClaim will contest a claim on a merchant the caller owns
+func (a *MerchantHandler) DisputeClaim(c echo.Context) error {
+	return a.moderate(c, a.MUsecase.DisputeClaim)
+}
+
+// ApproveClaim will grant a disputed claim
+func (a *MerchantHandler) ApproveClaim(c echo.Context) error {
+	return a.actOnID(c, a.MUsecase.ApproveClaim)
+}
+
+// RejectClaim will turn down a claim
+func (a *MerchantHandler) RejectClaim(c echo.Context) error {
+	return a.moderate(c, a.MUsecase.RejectClaim)
+}
+
+// FetchClaims will list the claims in a status, disputed by default
+func (a *MerchantHandler) FetchClaims(c echo.Context) error {
+	status := c.QueryParam("status")
+	if len(status) == 0 {
+		status = models.ClaimDisputed
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, err := a.MUsecase.FetchClaims(ctx, status)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+	})
+}
@@ -0,0 +1,384 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// geoJSONFlushEvery is how many features are written between flushes of the streamed response
+const geoJSONFlushEvery = 500
+
+type pointGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	ID         int64                  `json:"id"`
+	Geometry   pointGeometry          `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// parseBBox reads a bbox=minLng,minLat,maxLng,maxLat param
+func parseBBox(s string) (*models.BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, models.ErrBadParamInput
+	}
+	values := make([]float64, 4)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, models.ErrBadParamInput
+		}
+		values[i] = v
+	}
+	bbox := &models.BoundingBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+	if bbox.MinLng > bbox.MaxLng || bbox.MinLat > bbox.MaxLat {
+		return nil, models.ErrBadParamInput
+	}
+	return bbox, nil
+}
+
+// parseIDs reads a comma separated list of ids, an empty or "null" param means no filter
+func parseIDs(s string) ([]int64, error) {
+	if len(s) == 0 || s == "null" {
+		return nil, nil
+	}
+	ids := make([]int64, 0)
+	for _, p := range strings.Split(s, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
+		if err != nil {
+			return nil, models.ErrBadParamInput
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// featureProperties flattens a merchant into its JSON fields, keeping only the selected ones when any are given
+func featureProperties(m *models.Merchant, selected map[string]bool) (map[string]interface{}, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return nil, err
+	}
+	delete(props, "latitude")
+	delete(props, "longitude")
+	if len(selected) != 0 {
+		for key := range props {
+			if !selected[key] {
+				delete(props, key)
+			}
+		}
+	}
+	return props, nil
+}
+
+// GeoJSON will stream the located merchants matching the FilterByMulti filters as a FeatureCollection
+func (a *MerchantHandler) GeoJSON(c echo.Context) error {
+	var bbox *models.BoundingBox
+	if s := c.QueryParam("bbox"); len(s) != 0 {
+		parsed, err := parseBBox(s)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+		}
+		bbox = parsed
+	}
+	selected := map[string]bool{}
+	if s := c.QueryParam("properties"); len(s) != 0 {
+		for _, key := range strings.Split(s, ",") {
+			selected[strings.TrimSpace(key)] = true
+		}
+	}
+	filter := merchantFilter(c.QueryParams(), "page", "offset", "bbox", "properties")
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/geo+json")
+	written := 0
+	enc := json.NewEncoder(res)
+	err := a.MUsecase.StreamLocated(ctx, filter, bbox, func(m *models.Merchant) error {
+		props, err := featureProperties(m, selected)
+		if err != nil {
+			return err
+		}
+		if written == 0 {
+			res.WriteHeader(http.StatusOK)
+			if _, err := res.Write([]byte(`{"type":"FeatureCollection","features":[`)); err != nil {
+				return err
+			}
+		} else if _, err := res.Write([]byte(",")); err != nil {
+			return err
+		}
+		written++
+		if err := enc.Encode(&feature{
+			Type:       "Feature",
+			ID:         m.ID,
+			Geometry:   pointGeometry{Type: "Point", Coordinates: [2]float64{m.Longitude.Float64, m.Latitude.Float64}},
+			Properties: props,
+		}); err != nil {
+			return err
+		}
+		if written%geoJSONFlushEvery == 0 {
+			res.Flush()
+		}
+		return nil
+	})
+	if err != nil && written == 0 {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	if err != nil {
+		// the status line is already sent, all that is left is to cut the body short
+		logging.LoggerFromContext(c.Request().Context()).Error(err)
+		return nil
+	}
+	if written == 0 {
+		return c.Blob(http.StatusOK, "application/geo+json", []byte(`{"type":"FeatureCollection","features":[]}`))
+	}
+	_, err = res.Write([]byte("]}"))
+	return err
+}
+
+// Clusters will group the merchants inside bbox for the given map zoom level
+func (a *MerchantHandler) Clusters(c echo.Context) error {
+	bbox, err := parseBBox(c.QueryParam("bbox"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	zoom, err := strconv.Atoi(c.QueryParam("zoom"))
+	if err != nil || zoom < 0 || zoom > 22 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	categoryIDs, err := parseIDs(c.QueryParam("mb_category_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	areaIDs, err := parseIDs(c.QueryParam("area_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	clusters, err := a.MUsecase.Clusters(ctx, bbox, zoom, categoryIDs, areaIDs)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"zoom":   zoom,
+		"data":   clusters,
+	})
+}
+
+// parsePoint reads the lat and lng query params
+func parsePoint(c echo.Context) (float64, float64, error) {
+	lat, err := strconv.ParseFloat(c.QueryParam("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, models.ErrBadParamInput
+	}
+	lng, err := strconv.ParseFloat(c.QueryParam("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, models.ErrBadParamInput
+	}
+	return lat, lng, nil
+}
+
+// FetchDeliveryZones will fetch the delivery zones of a merchant
+func (a *MerchantHandler) FetchDeliveryZones(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	zones, err := a.MUsecase.FetchDeliveryZones(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   zones,
+	})
+}
+
+// ReplaceDeliveryZones will replace every delivery zone of a merchant with the given list
+func (a *MerchantHandler) ReplaceDeliveryZones(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	zones := make([]*models.DeliveryZone, 0)
+	if err := json.NewDecoder(c.Request().Body).Decode(&zones); err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := a.MUsecase.ReplaceDeliveryZones(ctx, int64(idP), zones); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   zones,
+	})
+}
+
+// DeliversTo will fetch the merchants delivering to the given point
+func (a *MerchantHandler) DeliversTo(c echo.Context) error {
+	lat, lng, err := parsePoint(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	categoryIDs, err := parseIDs(c.QueryParam("mb_category_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	listAr, err := a.MUsecase.DeliversTo(ctx, lat, lng, categoryIDs)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+		"total":  len(listAr),
+	})
+}
+
+// GetDeliveryPricing will fetch the delivery pricing rules of a merchant
+func (a *MerchantHandler) GetDeliveryPricing(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	pricing, err := a.MUsecase.GetDeliveryPricing(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   pricing,
+	})
+}
+
+// StoreDeliveryPricing will set the delivery pricing rules of a merchant
+func (a *MerchantHandler) StoreDeliveryPricing(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var pricing models.DeliveryPricing
+	if err := c.Bind(&pricing); err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	pricing.MerchantID = int64(idP)
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := a.MUsecase.StoreDeliveryPricing(ctx, &pricing); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   pricing,
+	})
+}
+
+// DeliveryQuote will estimate the delivery fee and time from a merchant to the given point
+func (a *MerchantHandler) DeliveryQuote(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	lat, lng, err := parsePoint(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	subtotal := 0.0
+	if s := c.QueryParam("subtotal"); len(s) != 0 {
+		if subtotal, err = strconv.ParseFloat(s, 64); err != nil || subtotal < 0 {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+		}
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	quote, err := a.MUsecase.DeliveryQuote(ctx, int64(idP), lat, lng, subtotal)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   quote,
+	})
+}
+
+// FetchOutsideArea will list the merchants located outside the boundary of their area
+func (a *MerchantHandler) FetchOutsideArea(c echo.Context) error {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	list, err := a.MUsecase.FetchOutsideArea(ctx)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   list,
+		"total":  len(list),
+	})
+}
+
+// LocateArea will find the area whose boundary holds the given point
+func (a *MerchantHandler) LocateArea(c echo.Context) error {
+	lat, lng, err := parsePoint(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ar, err := a.MUsecase.LocateArea(ctx, lat, lng)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   ar,
+	})
+}
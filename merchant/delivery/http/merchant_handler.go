@@ -2,14 +2,17 @@ package http
 
 import (
 	"context"
+	"fmt"
+	"merchant-service/logging"
 	"merchant-service/merchant"
 	"merchant-service/models"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo"
-	"github.com/sirupsen/logrus"
 )
 
 // ResponseError represent the reseponse error struct
@@ -17,6 +20,22 @@ type ResponseError struct {
 	Message string `json:"message"`
 }
 
+// mergeRequest represent the request body of a duplicate merge
+type mergeRequest struct {
+	SurvivorID int64 `json:"survivor_id"`
+	MergedID   int64 `json:"merged_id"`
+}
+
+// ownerRequest represent the request body adding an owner to a merchant
+type ownerRequest struct {
+	Subject string `json:"subject"`
+}
+
+// statusRequest represent the request body of a moderation action
+type statusRequest struct {
+	Reason string `json:"reason"`
+}
+
 // MerchantHandler  represent the httphandler for merchant
 type MerchantHandler struct {
 	MUsecase merchant.Usecase
@@ -28,11 +47,56 @@ func NewMerchantHandler(e *echo.Echo, us merchant.Usecase) {
 		MUsecase: us,
 	}
 	e.GET("/merchant/merchants", handler.FetchMerchant)
+	e.POST("/merchant", handler.Store)
+	e.PUT("/merchant/:id", handler.Update)
+	e.DELETE("/merchant/:id", handler.Delete)
+	e.POST("/merchant/:id/images", handler.StoreImage)
+	e.POST("/merchant/categories", handler.StoreCategory)
+	e.PUT("/merchant/categories/:id", handler.UpdateCategory)
+	e.DELETE("/merchant/categories/:id", handler.DeleteCategory)
+	e.POST("/merchant/area", handler.StoreArea)
+	e.PUT("/merchant/area/:id", handler.UpdateArea)
+	e.DELETE("/merchant/area/:id", handler.DeleteArea)
 	e.GET("/merchant/area", handler.FetchArea)
+	e.GET("/merchant/area/locate", handler.LocateArea)
 	e.GET("/merchant/:id", handler.GetByID)
+	e.GET("/merchant/by-slug/:slug", handler.GetBySlug)
+	e.POST("/merchant/admin/slugs/backfill", handler.BackfillSlugs)
 	e.GET("/merchant/filter", handler.FilterByMulti)
+	e.GET("/merchant/geojson", handler.GeoJSON)
+	e.GET("/merchant/clusters", handler.Clusters)
+	e.GET("/merchant/delivers-to", handler.DeliversTo)
+	e.GET("/merchant/:id/delivery-zones", handler.FetchDeliveryZones)
+	e.PUT("/merchant/:id/delivery-zones", handler.ReplaceDeliveryZones)
+	e.GET("/merchant/:id/delivery-pricing", handler.GetDeliveryPricing)
+	e.PUT("/merchant/:id/delivery-pricing", handler.StoreDeliveryPricing)
+	e.GET("/merchant/:id/delivery-quote", handler.DeliveryQuote)
 	e.GET("/merchant/search", handler.SearchByKeyword)
 	e.GET("/merchant/categories", handler.FetchCategories)
+	e.GET("/merchant/changes", handler.FetchChanges)
+	e.POST("/merchant/:id/submit", handler.Submit)
+	e.GET("/merchant/:id/revisions", handler.FetchRevisions)
+	e.GET("/merchant/:id/revisions/diff", handler.DiffRevisions)
+	e.POST("/merchant/:id/revisions/:revision/revert", handler.Revert)
+	e.POST("/merchant/:id/claims", handler.RequestClaim)
+	e.POST("/merchant/claims/:id/verify", handler.VerifyClaim)
+	e.POST("/merchant/claims/:id/dispute", handler.DisputeClaim)
+	e.GET("/merchant/admin/claims", handler.FetchClaims)
+	e.POST("/merchant/admin/claims/:id/approve", handler.ApproveClaim)
+	e.POST("/merchant/admin/claims/:id/reject", handler.RejectClaim)
+	e.GET("/merchant/admin/merchants", handler.FetchByStatus)
+	e.GET("/merchant/admin/outside-area", handler.FetchOutsideArea)
+	e.GET("/merchant/admin/duplicates", handler.FetchDuplicateCandidates)
+	e.POST("/merchant/admin/duplicates/detect", handler.DetectDuplicates)
+	e.POST("/merchant/admin/duplicates/merge", handler.Merge)
+	e.POST("/merchant/admin/duplicates/:id/dismiss", handler.DismissDuplicate)
+	e.GET("/merchant/admin/:id/transitions", handler.FetchStatusTransitions)
+	e.GET("/merchant/admin/:id/owners", handler.FetchOwners)
+	e.POST("/merchant/admin/:id/owners", handler.AddOwner)
+	e.DELETE("/merchant/admin/:id/owners/:subject", handler.RemoveOwner)
+	e.POST("/merchant/admin/:id/approve", handler.Approve)
+	e.POST("/merchant/admin/:id/reject", handler.Reject)
+	e.POST("/merchant/admin/:id/suspend", handler.Suspend)
 }
 
 // FetchArea will fetch the merchant based on given params
@@ -43,7 +107,7 @@ func (a *MerchantHandler) FetchArea(c echo.Context) error {
 	}
 	listAr, err := a.MUsecase.FetchArea(ctx)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
 	}
 	return c.JSON(http.StatusOK, echo.Map{
 		"status": 1,
@@ -59,7 +123,7 @@ func (a *MerchantHandler) FetchCategories(c echo.Context) error {
 	}
 	listAr, err := a.MUsecase.FetchCategories(ctx)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
 	}
 	return c.JSON(http.StatusOK, echo.Map{
 		"status": 1,
@@ -77,7 +141,7 @@ func (a *MerchantHandler) FetchMerchant(c echo.Context) error {
 	}
 	listAr, count, err := a.MUsecase.Fetch(ctx, page, offset)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
 	}
 	if len(page) != 0 && len(offset) != 0 {
 		return c.JSON(http.StatusOK, echo.Map{
@@ -95,9 +159,115 @@ func (a *MerchantHandler) FetchMerchant(c echo.Context) error {
 	})
 }
 
+// FetchChanges will fetch the merchants upserted or deleted since the given timestamp or cursor
+func (a *MerchantHandler) FetchChanges(c echo.Context) error {
+	since := time.Time{}
+	if s := c.QueryParam("since"); len(s) != 0 {
+		parsed, err := parseSince(s)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+		}
+		since = parsed
+	}
+	limit := 0
+	if l := c.QueryParam("limit"); len(l) != 0 {
+		parsed, err := strconv.Atoi(l)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+		}
+		limit = parsed
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	changes, err := a.MUsecase.FetchChanges(ctx, since, c.QueryParam("cursor"), limit)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   changes,
+	})
+}
+
+// parseSince accepts either an RFC3339 timestamp or unix seconds
+func parseSince(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 // Store new merchant to database
 func (a *MerchantHandler) Store(c echo.Context) error {
-	return c.String(http.StatusOK, "Store")
+	var m models.Merchant
+	if err := c.Bind(&m); err != nil || !m.Name.Valid || len(m.Name.String) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.Store(ctx, &m); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, a.withSuggestedArea(ctx, echo.Map{
+		"status": 1,
+		"data":   m,
+	}, &m))
+}
+
+// Update an merchant by id
+func (a *MerchantHandler) Update(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var m models.Merchant
+	if err := c.Bind(&m); err != nil || !m.Name.Valid || len(m.Name.String) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	m.ID = int64(idP)
+	ifMatch := c.Request().Header.Get("If-Match")
+	if len(ifMatch) == 0 {
+		return c.JSON(http.StatusPreconditionRequired, ResponseError{Message: models.ErrPreconditionRequired.Error()})
+	}
+	if m.Version, err = parseETag(ifMatch); err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.Update(ctx, &m); err != nil {
+		if err == models.ErrConflict {
+			setETag(c, &m)
+			return c.JSON(http.StatusConflict, echo.Map{
+				"status":  0,
+				"message": err.Error(),
+				"data":    m,
+			})
+		}
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	setETag(c, &m)
+	return c.JSON(http.StatusOK, a.withSuggestedArea(ctx, echo.Map{
+		"status": 1,
+		"data":   m,
+	}, &m))
+}
+
+// withSuggestedArea adds to a write response the area the merchant's coordinates point to, when
+// it differs from the one it was saved with
+func (a *MerchantHandler) withSuggestedArea(ctx context.Context, resp echo.Map, m *models.Merchant) echo.Map {
+	if ar, err := a.MUsecase.SuggestArea(ctx, m); err == nil {
+		resp["suggested_area"] = ar
+	}
+	return resp
 }
 
 // GetByID an merchant by id
@@ -114,50 +284,103 @@ func (a *MerchantHandler) GetByID(c echo.Context) error {
 	}
 
 	mers, err := a.MUsecase.GetByID(ctx, id)
+	if err == models.ErrNotFound {
+		// merchants merged into another one keep answering with a redirect to the survivor
+		if survivorID, mergedErr := a.MUsecase.GetMergedInto(ctx, id); mergedErr == nil {
+			return c.Redirect(http.StatusMovedPermanently, fmt.Sprintf("/merchant/%d", survivorID))
+		}
+	}
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
 	}
 
+	setETag(c, mers)
+	setLastModified(c, mers)
 	return c.JSON(http.StatusOK, echo.Map{
 		"status": 1,
 		"data":   mers,
 	})
 }
 
-// FilterByMulti some merchant by clause
-func (a *MerchantHandler) FilterByMulti(c echo.Context) error {
-	queryParams := c.QueryParams()
-	page := c.QueryParam("page")
-	offset := c.QueryParam("offset")
-	queryParamsSlice := []string{}
+// GetBySlug an merchant by its current or a former slug
+func (a *MerchantHandler) GetBySlug(c echo.Context) error {
+	slug := c.Param("slug")
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	mers, err := a.MUsecase.GetBySlug(ctx, slug)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	if mers.Slug.String != slug {
+		// the merchant was renamed, send clients to its current URL
+		return c.Redirect(http.StatusMovedPermanently, "/merchant/by-slug/"+mers.Slug.String)
+	}
+
+	setETag(c, mers)
+	setLastModified(c, mers)
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   mers,
+	})
+}
+
+// BackfillSlugs will generate slugs for merchants that have none
+func (a *MerchantHandler) BackfillSlugs(c echo.Context) error {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	count, err := a.MUsecase.BackfillSlugs(ctx)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), echo.Map{
+			"message": err.Error(),
+			"total":   count,
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"total":  count,
+	})
+}
+
+// merchantFilter turns the filter query params, minus the skipped ones, into the filter FilterByMulti
+// expects; "null" values are ignored and comma separated values match any of them
+func merchantFilter(queryParams url.Values, skip ...string) *models.MerchantFilter {
+	skipped := map[string]bool{}
+	for _, key := range skip {
+		skipped[key] = true
+	}
+	filter := &models.MerchantFilter{Fields: map[string][]string{}}
 	for key, value := range queryParams {
-		if key != "page" && key != "offset" {
-			if key == "keyword" {
-				if value[0] != "null" {
-					queryParamsSlice = append(queryParamsSlice, "name like \"%"+value[0]+"%\"")
-					//queryParamsSearch := fmt.Sprintf("name like CONCAT('%s', CONVERT('%s', BINARY), '%s')", "%", value[0], "%")
-					//fmt.Println(queryParamsSearch)
-					//queryParamsSlice = append(queryParamsSlice, queryParamsSlice)
-				}
-			} else if value[0] != "null" {
-				if strings.Contains(value[0], ",") {
-					queryParamsSlice = append(queryParamsSlice, key+" in ("+value[0]+")")
-				} else {
-					queryParamsSlice = append(queryParamsSlice, key+"="+value[0])
-				}
-			}
+		if skipped[key] || value[0] == "null" {
+			continue
+		}
+		if key == "keyword" {
+			filter.Keyword = value[0]
+			continue
 		}
+		filter.Fields[key] = strings.Split(value[0], ",")
 	}
-	queryParamsString := strings.Join(queryParamsSlice[:], " AND ")
+	return filter
+}
+
+// FilterByMulti some merchant by the query params
+func (a *MerchantHandler) FilterByMulti(c echo.Context) error {
+	page := c.QueryParam("page")
+	offset := c.QueryParam("offset")
+	filter := merchantFilter(c.QueryParams(), "page", "offset")
 
 	ctx := c.Request().Context()
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	listAr, count, err := a.MUsecase.FilterByMulti(ctx, queryParamsString, page, offset)
+	listAr, count, err := a.MUsecase.FilterByMulti(ctx, filter, page, offset)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
 	}
 
 	if len(page) != 0 && len(offset) != 0 {
@@ -189,7 +412,166 @@ func (a *MerchantHandler) SearchByKeyword(c echo.Context) error {
 
 	listAr, count, err := a.MUsecase.SearchByKeyword(ctx, keyword)
 	if err != nil {
-		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+		"total":  count,
+	})
+}
+
+// FetchRevisions will fetch every stored revision of a merchant, newest first
+func (a *MerchantHandler) FetchRevisions(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, err := a.MUsecase.FetchRevisions(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+	})
+}
+
+// DiffRevisions will compare two revisions of a merchant
+func (a *MerchantHandler) DiffRevisions(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	from, err := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	to, err := strconv.ParseInt(c.QueryParam("to"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	changes, err := a.MUsecase.DiffRevisions(ctx, int64(idP), from, to)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   changes,
+	})
+}
+
+// Revert a merchant to an earlier revision
+func (a *MerchantHandler) Revert(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mers, err := a.MUsecase.Revert(ctx, int64(idP), revision)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	setETag(c, mers)
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   mers,
+	})
+}
+
+// DetectDuplicates will rescore the catalog for duplicate merchants
+func (a *MerchantHandler) DetectDuplicates(c echo.Context) error {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	count, err := a.MUsecase.DetectDuplicates(ctx)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"total":  count,
+	})
+}
+
+// FetchDuplicateCandidates will fetch the pending duplicate pairs, best match first
+func (a *MerchantHandler) FetchDuplicateCandidates(c echo.Context) error {
+	minScore := 0.0
+	if s := c.QueryParam("min_score"); len(s) != 0 {
+		parsed, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+		}
+		minScore = parsed
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, err := a.MUsecase.FetchDuplicateCandidates(ctx, minScore)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+	})
+}
+
+// DismissDuplicate marks a duplicate pair as distinct merchants
+func (a *MerchantHandler) DismissDuplicate(c echo.Context) error {
+	return a.actOnID(c, a.MUsecase.DismissDuplicate)
+}
+
+// Merge two duplicate merchants into one
+func (a *MerchantHandler) Merge(c echo.Context) error {
+	var req mergeRequest
+	if err := c.Bind(&req); err != nil || req.SurvivorID == 0 || req.MergedID == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mers, err := a.MUsecase.Merge(ctx, req.SurvivorID, req.MergedID)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	setETag(c, mers)
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   mers,
+	})
+}
+
+// FetchByStatus will fetch the merchants in the given moderation status
+func (a *MerchantHandler) FetchByStatus(c echo.Context) error {
+	status := c.QueryParam("status")
+	page := c.QueryParam("page")
+	offset := c.QueryParam("offset")
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, count, err := a.MUsecase.FetchByStatus(ctx, status, page, offset)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
 	}
 	return c.JSON(http.StatusOK, echo.Map{
 		"status": 1,
@@ -198,16 +580,283 @@ func (a *MerchantHandler) SearchByKeyword(c echo.Context) error {
 	})
 }
 
+// FetchStatusTransitions will fetch the moderation history of a merchant
+func (a *MerchantHandler) FetchStatusTransitions(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, err := a.MUsecase.FetchStatusTransitions(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+	})
+}
+
+// FetchOwners will list the owners of a merchant
+func (a *MerchantHandler) FetchOwners(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	listAr, err := a.MUsecase.FetchOwners(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   listAr,
+	})
+}
+
+// AddOwner will let a principal edit a merchant
+func (a *MerchantHandler) AddOwner(c echo.Context) error {
+	var req ownerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	return a.actOnID(c, func(ctx context.Context, id int64) error {
+		return a.MUsecase.AddOwner(ctx, id, req.Subject)
+	})
+}
+
+// RemoveOwner will take away a principal's ownership of a merchant
+func (a *MerchantHandler) RemoveOwner(c echo.Context) error {
+	return a.actOnID(c, func(ctx context.Context, id int64) error {
+		return a.MUsecase.RemoveOwner(ctx, id, c.Param("subject"))
+	})
+}
+
+// Submit a merchant for moderation
+func (a *MerchantHandler) Submit(c echo.Context) error {
+	return a.moderate(c, func(ctx context.Context, id int64, _ string) error {
+		return a.MUsecase.Submit(ctx, id)
+	})
+}
+
+// Approve a pending or suspended merchant
+func (a *MerchantHandler) Approve(c echo.Context) error {
+	return a.moderate(c, func(ctx context.Context, id int64, _ string) error {
+		return a.MUsecase.Approve(ctx, id)
+	})
+}
+
+// Reject a pending merchant with a reason
+func (a *MerchantHandler) Reject(c echo.Context) error {
+	return a.moderate(c, a.MUsecase.Reject)
+}
+
+// Suspend an approved merchant with a reason
+func (a *MerchantHandler) Suspend(c echo.Context) error {
+	return a.moderate(c, a.MUsecase.Suspend)
+}
+
+func (a *MerchantHandler) moderate(c echo.Context, action func(ctx context.Context, id int64, reason string) error) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var req statusRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+		}
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := action(ctx, int64(idP), req.Reason); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+	})
+}
+
 // Delete an merchant by id
 func (a *MerchantHandler) Delete(c echo.Context) error {
-	return c.String(http.StatusOK, "delete")
+	return a.actOnID(c, a.MUsecase.Delete)
+}
+
+// StoreImage add an image to a merchant
+func (a *MerchantHandler) StoreImage(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var img models.Image
+	if err := c.Bind(&img); err != nil || len(img.Image) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	img.MerchantID = int64(idP)
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.StoreImage(ctx, &img); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{
+		"status": 1,
+		"data":   img,
+	})
+}
+
+// StoreCategory new category to database
+func (a *MerchantHandler) StoreCategory(c echo.Context) error {
+	var m models.MbDiscoveryCategory
+	if err := c.Bind(&m); err != nil || len(m.Name) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.StoreCategory(ctx, &m); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{
+		"status": 1,
+		"data":   m,
+	})
+}
+
+// UpdateCategory an category by id
+func (a *MerchantHandler) UpdateCategory(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var m models.MbDiscoveryCategory
+	if err := c.Bind(&m); err != nil || len(m.Name) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	m.ID = int64(idP)
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.UpdateCategory(ctx, &m); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   m,
+	})
+}
+
+// DeleteCategory an category by id
+func (a *MerchantHandler) DeleteCategory(c echo.Context) error {
+	return a.actOnID(c, a.MUsecase.DeleteCategory)
 }
 
-func getStatusCode(err error) int {
+// StoreArea new area to database
+func (a *MerchantHandler) StoreArea(c echo.Context) error {
+	var m models.Area
+	if err := c.Bind(&m); err != nil || !m.Name.Valid || len(m.Name.String) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.StoreArea(ctx, &m); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{
+		"status": 1,
+		"data":   m,
+	})
+}
+
+// UpdateArea an area by id
+func (a *MerchantHandler) UpdateArea(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	var m models.Area
+	if err := c.Bind(&m); err != nil || !m.Name.Valid || len(m.Name.String) == 0 {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: models.ErrBadParamInput.Error()})
+	}
+	m.ID = int64(idP)
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := a.MUsecase.UpdateArea(ctx, &m); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+		"data":   m,
+	})
+}
+
+// DeleteArea an area by id
+func (a *MerchantHandler) DeleteArea(c echo.Context) error {
+	return a.actOnID(c, a.MUsecase.DeleteArea)
+}
+
+// actOnID runs an action that only needs the :id path parameter
+func (a *MerchantHandler) actOnID(c echo.Context, action func(ctx context.Context, id int64) error) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := action(ctx, int64(idP)); err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": 1,
+	})
+}
+
+// setETag exposes the merchant version as a strong entity tag for If-Match
+func setETag(c echo.Context, m *models.Merchant) {
+	c.Response().Header().Set("ETag", fmt.Sprintf("\"%d\"", m.Version))
+}
+
+// setLastModified lets clients revalidate a merchant with If-Modified-Since
+func setLastModified(c echo.Context, m *models.Merchant) {
+	if m.UpdatedAt.Valid {
+		c.Response().Header().Set("Last-Modified", m.UpdatedAt.Time.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseETag reads the merchant version back out of an If-Match value
+func parseETag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	return strconv.ParseInt(strings.Trim(tag, "\""), 10, 64)
+}
+
+func getStatusCode(c echo.Context, err error) int {
 	if err == nil {
 		return http.StatusOK
 	}
-	logrus.Error(err)
+	logging.LoggerFromContext(c.Request().Context()).Error(err)
 	switch err {
 	case models.ErrInternalServerError:
 		return http.StatusInternalServerError
@@ -215,6 +864,18 @@ func getStatusCode(err error) int {
 		return http.StatusNotFound
 	case models.ErrConflict:
 		return http.StatusConflict
+	case models.ErrBadParamInput:
+		return http.StatusBadRequest
+	case models.ErrInvalidTransition:
+		return http.StatusConflict
+	case models.ErrPreconditionRequired:
+		return http.StatusPreconditionRequired
+	case models.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case models.ErrForbidden:
+		return http.StatusForbidden
+	case models.ErrTooManyRequests:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -0,0 +1,282 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"gopkg.in/guregu/null.v3"
+
+	"merchant-service/logging"
+	"merchant-service/merchant"
+	"merchant-service/models"
+)
+
+// sitemapLimit is the most URLs the sitemap protocol allows in one file
+const sitemapLimit = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// sitemapFlushEvery is how many URLs are written between flushes of the streamed sitemap
+const sitemapFlushEvery = 1000
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name      `xml:"sitemapindex"`
+	Xmlns    string        `xml:"xmlns,attr"`
+	Sitemaps []*sitemapURL `xml:"sitemap"`
+}
+
+type geoCoordinates struct {
+	Type      string  `json:"@type"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type postalAddress struct {
+	Type          string `json:"@type"`
+	StreetAddress string `json:"streetAddress"`
+}
+
+type openingHours struct {
+	Type      string   `json:"@type"`
+	DayOfWeek []string `json:"dayOfWeek"`
+	Opens     string   `json:"opens"`
+	Closes    string   `json:"closes"`
+}
+
+// localBusiness represent the schema.org LocalBusiness JSON-LD of a merchant
+type localBusiness struct {
+	Context      string          `json:"@context"`
+	Type         string          `json:"@type"`
+	ID           string          `json:"@id"`
+	Name         string          `json:"name"`
+	URL          string          `json:"url"`
+	Description  string          `json:"description,omitempty"`
+	Telephone    string          `json:"telephone,omitempty"`
+	Address      *postalAddress  `json:"address,omitempty"`
+	Geo          *geoCoordinates `json:"geo,omitempty"`
+	Image        []string        `json:"image,omitempty"`
+	OpeningHours []*openingHours `json:"openingHoursSpecification,omitempty"`
+	SameAs       []string        `json:"sameAs,omitempty"`
+}
+
+var everyDay = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// SEOHandler  represent the httphandler for search engine exports of merchants
+type SEOHandler struct {
+	MUsecase merchant.Usecase
+	BaseURL  string
+}
+
+// NewSEOHandler will initialize the sitemap and structured-data endpoints, baseURL is the public web origin
+func NewSEOHandler(e *echo.Echo, us merchant.Usecase, baseURL string) {
+	handler := &SEOHandler{
+		MUsecase: us,
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+	}
+	e.GET("/sitemap.xml", handler.Sitemap)
+	e.GET("/sitemaps/:file", handler.SitemapPage)
+	e.GET("/merchant/:id/jsonld", handler.JSONLD)
+}
+
+func (a *SEOHandler) merchantURL(m *models.Merchant) string {
+	if m.Slug.Valid {
+		return a.BaseURL + "/merchant/" + m.Slug.String
+	}
+	return fmt.Sprintf("%s/merchant/%d", a.BaseURL, m.ID)
+}
+
+func (a *SEOHandler) absoluteURL(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return a.BaseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+func (a *SEOHandler) writeXML(c echo.Context, v interface{}) error {
+	out, err := xml.Marshal(v)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ResponseError{Message: err.Error()})
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), out...))
+}
+
+// approvedCount counts the public merchants; only the count of a one-row page is used
+func (a *SEOHandler) approvedCount(ctx context.Context) (int64, error) {
+	_, count, err := a.MUsecase.FetchApproved(ctx, "1", "1")
+	return count, err
+}
+
+// writeURLSet streams the URLs of one sitemap page, the rows are never all held in memory
+func (a *SEOHandler) writeURLSet(ctx context.Context, c echo.Context, page int) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationXMLCharsetUTF8)
+	written := 0
+	enc := xml.NewEncoder(res)
+	start := func() error {
+		res.WriteHeader(http.StatusOK)
+		_, err := res.Write([]byte(xml.Header + `<urlset xmlns="` + sitemapNamespace + `">`))
+		return err
+	}
+	err := a.MUsecase.StreamApproved(ctx, page, sitemapLimit, func(m *models.Merchant) error {
+		if written == 0 {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		written++
+		u := &sitemapURL{Loc: a.merchantURL(m)}
+		if m.UpdatedAt.Valid {
+			u.LastMod = m.UpdatedAt.Time.UTC().Format(time.RFC3339)
+		}
+		if err := enc.EncodeElement(u, xml.StartElement{Name: xml.Name{Local: "url"}}); err != nil {
+			return err
+		}
+		if written%sitemapFlushEvery == 0 {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			res.Flush()
+		}
+		return nil
+	})
+	if err != nil && written == 0 {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	if err != nil {
+		// the status line is already sent, all that is left is to cut the body short
+		logging.LoggerFromContext(c.Request().Context()).Error(err)
+		return nil
+	}
+	if written == 0 {
+		if err := start(); err != nil {
+			return err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = res.Write([]byte(`</urlset>`))
+	return err
+}
+
+// Sitemap will serve the merchant sitemap, or a sitemap index once the catalog passes the per-file limit
+func (a *SEOHandler) Sitemap(c echo.Context) error {
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	count, err := a.approvedCount(ctx)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	if count <= sitemapLimit {
+		return a.writeURLSet(ctx, c, 1)
+	}
+
+	index := &sitemapIndex{Xmlns: sitemapNamespace}
+	pages := int((count + sitemapLimit - 1) / sitemapLimit)
+	for page := 1; page <= pages; page++ {
+		index.Sitemaps = append(index.Sitemaps, &sitemapURL{Loc: fmt.Sprintf("%s/sitemaps/merchants-%d.xml", a.BaseURL, page)})
+	}
+	return a.writeXML(c, index)
+}
+
+// SitemapPage will serve one file of a split sitemap, named merchants-<page>.xml
+func (a *SEOHandler) SitemapPage(c echo.Context) error {
+	file := c.Param("file")
+	if !strings.HasPrefix(file, "merchants-") || !strings.HasSuffix(file, ".xml") {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: models.ErrNotFound.Error()})
+	}
+	page, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(file, "merchants-"), ".xml"))
+	if err != nil || page < 1 {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: models.ErrNotFound.Error()})
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	count, err := a.approvedCount(ctx)
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+	if int64(page-1)*sitemapLimit >= count {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: models.ErrNotFound.Error()})
+	}
+	return a.writeURLSet(ctx, c, page)
+}
+
+// clockTime reads "08:00" or "08:00:00" as the HH:MM schema.org expects
+func clockTime(s null.String) (string, bool) {
+	if !s.Valid || len(s.String) < 5 {
+		return "", false
+	}
+	if _, err := time.Parse("15:04", s.String[:5]); err != nil {
+		return "", false
+	}
+	return s.String[:5], true
+}
+
+// JSONLD will serve the schema.org LocalBusiness description of an approved merchant
+func (a *SEOHandler) JSONLD(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.ErrNotFound.Error())
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	m, err := a.MUsecase.GetByID(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(c, err), ResponseError{Message: err.Error()})
+	}
+
+	url := a.merchantURL(m)
+	ld := &localBusiness{
+		Context:     "https://schema.org",
+		Type:        "LocalBusiness",
+		ID:          url,
+		Name:        m.Name.String,
+		URL:         url,
+		Description: m.Description.String,
+		Telephone:   m.Phone.String,
+	}
+	if m.Address.Valid && len(m.Address.String) != 0 {
+		ld.Address = &postalAddress{Type: "PostalAddress", StreetAddress: m.Address.String}
+	}
+	if m.Latitude.Valid && m.Longitude.Valid {
+		ld.Geo = &geoCoordinates{Type: "GeoCoordinates", Latitude: m.Latitude.Float64, Longitude: m.Longitude.Float64}
+	}
+	if m.Image.Valid && len(m.Image.String) != 0 {
+		ld.Image = append(ld.Image, a.absoluteURL(m.Image.String))
+	}
+	for _, img := range m.Images {
+		ld.Image = append(ld.Image, a.absoluteURL(img.Image))
+	}
+	opens, okOpens := clockTime(m.TimeStart)
+	closes, okCloses := clockTime(m.TimeEnd)
+	if okOpens && okCloses {
+		ld.OpeningHours = []*openingHours{{Type: "OpeningHoursSpecification", DayOfWeek: everyDay, Opens: opens, Closes: closes}}
+	}
+	if m.Facebook.Valid && len(m.Facebook.String) != 0 {
+		ld.SameAs = []string{m.Facebook.String}
+	}
+
+	out, err := json.Marshal(ld)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ResponseError{Message: err.Error()})
+	}
+	return c.Blob(http.StatusOK, "application/ld+json; charset=UTF-8", out)
+}
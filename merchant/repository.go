@@ -2,6 +2,7 @@ package merchant
 
 import (
 	"context"
+	"time"
 
 	models "merchant-service/models"
 )
@@ -9,14 +10,58 @@ import (
 // Repository represent the merchant's repository contract
 type Repository interface {
 	Fetch(ctx context.Context, page string, offset string) (res []*models.Merchant, count int64, err error)
+	FetchChanges(ctx context.Context, since time.Time, afterID int64, limit int) ([]*models.Merchant, error)
+	FetchByStatus(ctx context.Context, status string, page string, offset string) ([]*models.Merchant, int64, error)
+	UpdateStatus(ctx context.Context, t *models.MerchantStatusTransition) error
+	FetchStatusTransitions(ctx context.Context, merchantID int64) ([]*models.MerchantStatusTransition, error)
+	FetchOwners(ctx context.Context, id int64) ([]*models.MerchantOwner, error)
+	IsOwner(ctx context.Context, id int64, subject string) (bool, error)
+	StoreOwner(ctx context.Context, o *models.MerchantOwner) error
+	DeleteOwner(ctx context.Context, id int64, subject string) error
+	FetchClaims(ctx context.Context, status string) ([]*models.MerchantClaim, error)
+	FetchMerchantClaims(ctx context.Context, id int64) ([]*models.MerchantClaim, error)
+	CountSubjectClaims(ctx context.Context, subject string, since time.Time) (int64, error)
+	GetClaim(ctx context.Context, id int64) (*models.MerchantClaim, error)
+	StoreClaim(ctx context.Context, c *models.MerchantClaim) error
+	UpdateClaim(ctx context.Context, c *models.MerchantClaim, from string) error
+	ApplyClaim(ctx context.Context, c *models.MerchantClaim, from string, exclusive bool) error
 	FetchCategories(ctx context.Context) (res []*models.MbDiscoveryCategory, err error)
+	GetCategoryByID(ctx context.Context, id int64) (*models.MbDiscoveryCategory, error)
+	StoreCategory(ctx context.Context, m *models.MbDiscoveryCategory) error
+	UpdateCategory(ctx context.Context, m *models.MbDiscoveryCategory) error
+	DeleteCategory(ctx context.Context, id int64) error
 	FetchArea(ctx context.Context) (res []*models.Area, err error)
+	GetAreaByID(ctx context.Context, id int64) (*models.Area, error)
+	StoreArea(ctx context.Context, m *models.Area) error
+	UpdateArea(ctx context.Context, m *models.Area) error
+	DeleteArea(ctx context.Context, id int64) error
+	FetchActive(ctx context.Context) ([]*models.Merchant, error)
 	GetByID(ctx context.Context, id int64) (*models.Merchant, error)
+	ResolveSlug(ctx context.Context, slug string) (int64, error)
+	SetSlug(ctx context.Context, id int64, slug string) error
+	GetMergedInto(ctx context.Context, id int64) (int64, error)
+	Merge(ctx context.Context, survivorID int64, mergedID int64) error
+	ReplaceDuplicateCandidates(ctx context.Context, list []*models.DuplicateCandidate) error
+	FetchDuplicateCandidates(ctx context.Context, status string, minScore float64) ([]*models.DuplicateCandidate, error)
+	UpdateDuplicateStatus(ctx context.Context, id int64, status string) error
 	GetImagesByID(ctx context.Context, id int64) ([]*models.Image, error)
-	FilterByMulti(ctx context.Context, clause string, page string, offset string) ([]*models.Merchant, int64, error)
+	StoreImage(ctx context.Context, img *models.Image) error
+	ReplaceImages(ctx context.Context, id int64, images []*models.Image) error
+	StoreRevision(ctx context.Context, r *models.MerchantRevision) error
+	Revert(ctx context.Context, m *models.Merchant, r *models.MerchantRevision) error
+	GetRevision(ctx context.Context, id int64, revision int64) (*models.MerchantRevision, error)
+	FetchRevisions(ctx context.Context, id int64) ([]*models.MerchantRevision, error)
+	FilterByMulti(ctx context.Context, filter *models.MerchantFilter, page string, offset string) ([]*models.Merchant, int64, error)
+	StreamLocated(ctx context.Context, filter *models.MerchantFilter, bbox *models.BoundingBox, fn func(*models.Merchant) error) error
+	StreamApproved(ctx context.Context, page int, limit int, fn func(*models.Merchant) error) error
+	FetchDeliveryZones(ctx context.Context, id int64) ([]*models.DeliveryZone, error)
+	FetchAllDeliveryZones(ctx context.Context) ([]*models.DeliveryZone, error)
+	ReplaceDeliveryZones(ctx context.Context, id int64, zones []*models.DeliveryZone) error
+	GetDeliveryPricing(ctx context.Context, id int64) (*models.DeliveryPricing, error)
+	StoreDeliveryPricing(ctx context.Context, p *models.DeliveryPricing) error
 	SearchByKeyword(ctx context.Context, title string) ([]*models.Merchant, int64, error)
 	Update(ctx context.Context, ar *models.Merchant) error
-	Store(ctx context.Context, a *models.Merchant) error
+	Store(ctx context.Context, a *models.Merchant, owner string) error
 	Delete(ctx context.Context, id int64) error
 	GetCountRows(ctx context.Context, clause string) (int64, error)
 }
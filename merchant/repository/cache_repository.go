@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"merchant-service/merchant"
+	"merchant-service/metrics"
+	"merchant-service/models"
+)
+
+const (
+	keyCategories = "categories"
+	keyAreas      = "areas"
+)
+
+// loadTimeout bounds a read shared by every caller missing the same key, which no single caller's
+// deadline may cut short
+const loadTimeout = 10 * time.Second
+
+func keyCategory(id int64) string { return fmt.Sprintf("category:%d", id) }
+func keyArea(id int64) string     { return fmt.Sprintf("area:%d", id) }
+func keyMerchant(id int64) string { return fmt.Sprintf("merchant:%d", id) }
+
+// CacheTTL holds how long each cached read is kept; a read with no TTL is not cached
+type CacheTTL struct {
+	Categories time.Duration
+	Areas      time.Duration
+	Merchant   time.Duration
+}
+
+// cachedMerchantRepository decorates a merchant.Repository with an in-memory cache of the category,
+// area and merchant reads. Writes through it drop the entries they touch; writes made by other
+// instances are only seen once the TTL runs out.
+type cachedMerchantRepository struct {
+	merchant.Repository
+	cache   *lruCache
+	group   singleflight.Group
+	ttl     CacheTTL
+	metrics *metrics.Cache
+}
+
+// NewCachedMerchantRepository will create an object that represent the merchant.Repository interface,
+// caching up to size reads of next and counting the hits and misses in m
+func NewCachedMerchantRepository(next merchant.Repository, ttl CacheTTL, size int, m *metrics.Cache) merchant.Repository {
+	return &cachedMerchantRepository{
+		Repository: next,
+		cache:      newLRUCache(size),
+		ttl:        ttl,
+		metrics:    m,
+	}
+}
+
+// cacheKind names the kind of entry of key, "merchant" for "merchant:42", for the metrics
+func cacheKind(key string) string {
+	return strings.SplitN(key, ":", 2)[0]
+}
+
+// detachedContext keeps the values of a context, such as its logger and span, but not its
+// cancellation or deadline
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// load returns the cached value of key, or runs fn once however many callers miss it at the same
+// time. The shared run is detached from ctx and bounded by loadTimeout, so the first caller giving up
+// does not fail the others waiting on it. Errors are never cached.
+func (r *cachedMerchantRepository) load(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if ttl <= 0 {
+		return fn(ctx)
+	}
+	if v, ok := r.cache.get(key); ok {
+		r.metrics.Hit(cacheKind(key))
+		return v, nil
+	}
+	r.metrics.Miss(cacheKind(key))
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(detachedContext{ctx}, loadTimeout)
+		defer cancel()
+		gen := r.cache.generation()
+		v, err := fn(loadCtx)
+		if err == nil {
+			r.cache.set(key, v, ttl, gen)
+		}
+		return v, err
+	})
+	return v, err
+}
+
+// the cached values are shared, so callers get copies they are free to change
+
+func copyCategories(list []*models.MbDiscoveryCategory) []*models.MbDiscoveryCategory {
+	res := make([]*models.MbDiscoveryCategory, 0, len(list))
+	for _, c := range list {
+		cp := *c
+		res = append(res, &cp)
+	}
+	return res
+}
+
+func copyArea(ar *models.Area) *models.Area {
+	cp := *ar
+	if ar.Boundary != nil {
+		cp.Boundary = append(models.Polygon(nil), ar.Boundary...)
+	}
+	return &cp
+}
+
+func copyAreas(list []*models.Area) []*models.Area {
+	res := make([]*models.Area, 0, len(list))
+	for _, ar := range list {
+		res = append(res, copyArea(ar))
+	}
+	return res
+}
+
+func copyMerchant(m *models.Merchant) *models.Merchant {
+	cp := *m
+	if m.Images != nil {
+		cp.Images = make([]*models.Image, 0, len(m.Images))
+		for _, img := range m.Images {
+			imgCp := *img
+			cp.Images = append(cp.Images, &imgCp)
+		}
+	}
+	return &cp
+}
+
+func (r *cachedMerchantRepository) FetchCategories(ctx context.Context) ([]*models.MbDiscoveryCategory, error) {
+	v, err := r.load(ctx, keyCategories, r.ttl.Categories, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchCategories(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyCategories(v.([]*models.MbDiscoveryCategory)), nil
+}
+
+func (r *cachedMerchantRepository) GetCategoryByID(ctx context.Context, id int64) (*models.MbDiscoveryCategory, error) {
+	v, err := r.load(ctx, keyCategory(id), r.ttl.Categories, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.GetCategoryByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cp := *v.(*models.MbDiscoveryCategory)
+	return &cp, nil
+}
+
+func (r *cachedMerchantRepository) FetchArea(ctx context.Context) ([]*models.Area, error) {
+	v, err := r.load(ctx, keyAreas, r.ttl.Areas, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.FetchArea(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyAreas(v.([]*models.Area)), nil
+}
+
+func (r *cachedMerchantRepository) GetAreaByID(ctx context.Context, id int64) (*models.Area, error) {
+	v, err := r.load(ctx, keyArea(id), r.ttl.Areas, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.GetAreaByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyArea(v.(*models.Area)), nil
+}
+
+func (r *cachedMerchantRepository) GetByID(ctx context.Context, id int64) (*models.Merchant, error) {
+	v, err := r.load(ctx, keyMerchant(id), r.ttl.Merchant, func(ctx context.Context) (interface{}, error) {
+		return r.Repository.GetByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyMerchant(v.(*models.Merchant)), nil
+}
+
+// Writes drop the entries they touch whether or not they succeed: a failed conditional write means
+// the cached copy is likely stale.
+
+func (r *cachedMerchantRepository) StoreCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	defer r.cache.remove(keyCategories)
+	return r.Repository.StoreCategory(ctx, m)
+}
+
+func (r *cachedMerchantRepository) UpdateCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	defer r.cache.remove(keyCategories, keyCategory(m.ID))
+	return r.Repository.UpdateCategory(ctx, m)
+}
+
+func (r *cachedMerchantRepository) DeleteCategory(ctx context.Context, id int64) error {
+	defer r.cache.remove(keyCategories, keyCategory(id))
+	return r.Repository.DeleteCategory(ctx, id)
+}
+
+func (r *cachedMerchantRepository) StoreArea(ctx context.Context, m *models.Area) error {
+	defer r.cache.remove(keyAreas)
+	return r.Repository.StoreArea(ctx, m)
+}
+
+func (r *cachedMerchantRepository) UpdateArea(ctx context.Context, m *models.Area) error {
+	defer r.cache.remove(keyAreas, keyArea(m.ID))
+	return r.Repository.UpdateArea(ctx, m)
+}
+
+func (r *cachedMerchantRepository) DeleteArea(ctx context.Context, id int64) error {
+	defer r.cache.remove(keyAreas, keyArea(id))
+	return r.Repository.DeleteArea(ctx, id)
+}
+
+func (r *cachedMerchantRepository) UpdateStatus(ctx context.Context, t *models.MerchantStatusTransition) error {
+	defer r.cache.remove(keyMerchant(t.MerchantID))
+	return r.Repository.UpdateStatus(ctx, t)
+}
+
+func (r *cachedMerchantRepository) SetSlug(ctx context.Context, id int64, slug string) error {
+	defer r.cache.remove(keyMerchant(id))
+	return r.Repository.SetSlug(ctx, id, slug)
+}
+
+func (r *cachedMerchantRepository) Merge(ctx context.Context, survivorID int64, mergedID int64) error {
+	defer r.cache.remove(keyMerchant(survivorID), keyMerchant(mergedID))
+	return r.Repository.Merge(ctx, survivorID, mergedID)
+}
+
+func (r *cachedMerchantRepository) StoreImage(ctx context.Context, img *models.Image) error {
+	defer r.cache.remove(keyMerchant(img.MerchantID))
+	return r.Repository.StoreImage(ctx, img)
+}
+
+func (r *cachedMerchantRepository) ReplaceImages(ctx context.Context, id int64, images []*models.Image) error {
+	defer r.cache.remove(keyMerchant(id))
+	return r.Repository.ReplaceImages(ctx, id, images)
+}
+
+func (r *cachedMerchantRepository) Revert(ctx context.Context, m *models.Merchant, rev *models.MerchantRevision) error {
+	defer r.cache.remove(keyMerchant(m.ID))
+	return r.Repository.Revert(ctx, m, rev)
+}
+
+func (r *cachedMerchantRepository) Update(ctx context.Context, m *models.Merchant) error {
+	defer r.cache.remove(keyMerchant(m.ID))
+	return r.Repository.Update(ctx, m)
+}
+
+func (r *cachedMerchantRepository) Delete(ctx context.Context, id int64) error {
+	defer r.cache.remove(keyMerchant(id))
+	return r.Repository.Delete(ctx, id)
+}
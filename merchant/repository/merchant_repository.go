@@ -3,38 +3,80 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/guregu/null.v3"
 
+	"merchant-service/logging"
 	"merchant-service/merchant"
 	"merchant-service/models"
 )
 
+const selectMerchant = `SELECT mb_merchant_id, name, slug, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook, status, version, created_at, updated_at, deleted_at FROM mb_merchant`
+
+// publicMerchant restricts a query to merchants visible on public endpoints
+const publicMerchant = `deleted_at is null AND status = 'approved'`
+
+// duplicateInsertBatch is the number of duplicate candidates written by one INSERT statement
+const duplicateInsertBatch = 500
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation
+const mysqlDuplicateEntry = 1062
+
 type mysqlMerchantRepository struct {
-	DB *sql.DB
+	DB *tracedDB
 }
 
 // NewMysqlMerchantRepository will create an object that represent the merchant.Repository interface
 func NewMysqlMerchantRepository(db *sql.DB) merchant.Repository {
 	return &mysqlMerchantRepository{
-		DB: db,
+		DB: &tracedDB{DB: db},
 	}
 }
 
+func scanMerchant(rows *sql.Rows, t *models.Merchant) error {
+	return rows.Scan(
+		&t.ID,
+		&t.Name,
+		&t.Slug,
+		&t.Address,
+		&t.Latitude,
+		&t.Longitude,
+		&t.Phone,
+		&t.Description,
+		&t.MbCategoryID,
+		&t.AreaID,
+		&t.Image,
+		&t.Delivery,
+		&t.TimeStart,
+		&t.TimeEnd,
+		&t.Facebook,
+		&t.Status,
+		&t.Version,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+		&t.DeletedAt,
+	)
+}
+
 func (a *mysqlMerchantRepository) fetch(ctx context.Context, query string, args ...interface{}) ([]*models.Merchant, error) {
 	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		logrus.Error(err)
+		logging.LoggerFromContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 		}
 	}()
 
@@ -42,24 +84,9 @@ func (a *mysqlMerchantRepository) fetch(ctx context.Context, query string, args
 
 	for rows.Next() {
 		t := new(models.Merchant)
-		err = rows.Scan(
-			&t.ID,
-			&t.Name,
-			&t.Address,
-			&t.Latitude,
-			&t.Longitude,
-			&t.Phone,
-			&t.Description,
-			&t.MbCategoryID,
-			&t.AreaID,
-			&t.Image,
-			&t.Delivery,
-			&t.TimeStart,
-			&t.TimeEnd,
-			&t.Facebook,
-		)
+		err = scanMerchant(rows, t)
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 			return nil, err
 		}
 		results = append(results, t)
@@ -71,14 +98,14 @@ func (a *mysqlMerchantRepository) fetch(ctx context.Context, query string, args
 func (a *mysqlMerchantRepository) fetchArea(ctx context.Context, query string, args ...interface{}) ([]*models.Area, error) {
 	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		logrus.Error(err)
+		logging.LoggerFromContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 		}
 	}()
 
@@ -86,17 +113,25 @@ func (a *mysqlMerchantRepository) fetchArea(ctx context.Context, query string, a
 
 	for rows.Next() {
 		t := new(models.Area)
+		var boundary []byte
 		err = rows.Scan(
 			&t.ID,
 			&t.Name,
 			&t.RegionID,
 			&t.Description,
 			&t.Image,
+			&boundary,
 		)
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 			return nil, err
 		}
+		if len(boundary) != 0 {
+			if err = json.Unmarshal(boundary, &t.Boundary); err != nil {
+				logging.LoggerFromContext(ctx).Error(err)
+				return nil, err
+			}
+		}
 		results = append(results, t)
 	}
 
@@ -106,14 +141,14 @@ func (a *mysqlMerchantRepository) fetchArea(ctx context.Context, query string, a
 func (a *mysqlMerchantRepository) fetchCategories(ctx context.Context, query string, args ...interface{}) ([]*models.MbDiscoveryCategory, error) {
 	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		logrus.Error(err)
+		logging.LoggerFromContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 		}
 	}()
 
@@ -129,7 +164,7 @@ func (a *mysqlMerchantRepository) fetchCategories(ctx context.Context, query str
 			&t.Image,
 		)
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 			return nil, err
 		}
 		results = append(results, t)
@@ -141,14 +176,14 @@ func (a *mysqlMerchantRepository) fetchCategories(ctx context.Context, query str
 func (a *mysqlMerchantRepository) fetchDetail(ctx context.Context, query string, id int64) ([]*models.Merchant, error) {
 	rows, err := a.DB.QueryContext(ctx, query)
 	if err != nil {
-		logrus.Error(err)
+		logging.LoggerFromContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 		}
 	}()
 
@@ -158,25 +193,10 @@ func (a *mysqlMerchantRepository) fetchDetail(ctx context.Context, query string,
 
 	for rows.Next() {
 		t := new(models.Merchant)
-		err = rows.Scan(
-			&t.ID,
-			&t.Name,
-			&t.Address,
-			&t.Latitude,
-			&t.Longitude,
-			&t.Phone,
-			&t.Description,
-			&t.MbCategoryID,
-			&t.AreaID,
-			&t.Image,
-			&t.Delivery,
-			&t.TimeStart,
-			&t.TimeEnd,
-			&t.Facebook,
-		)
+		err = scanMerchant(rows, t)
 		t.Images = images
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 			return nil, err
 		}
 		results = append(results, t)
@@ -186,7 +206,7 @@ func (a *mysqlMerchantRepository) fetchDetail(ctx context.Context, query string,
 }
 
 func (a *mysqlMerchantRepository) Fetch(ctx context.Context, page string, offset string) ([]*models.Merchant, int64, error) {
-	query := `SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant where is_deleted is null`
+	query := selectMerchant + ` where ` + publicMerchant
 	if len(page) != 0 && len(offset) != 0 {
 		pageInt, err := strconv.Atoi(page)
 		if err != nil {
@@ -199,11 +219,13 @@ func (a *mysqlMerchantRepository) Fetch(ctx context.Context, page string, offset
 		if pageInt < 0 || offsetInt < 0 {
 			return nil, 0, errors.New("Could not enter a negative number")
 		}
-		query = fmt.Sprintf("SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant where is_deleted is null ORDER BY mb_merchant_id ASC LIMIT %d, %s", (pageInt-1)*offsetInt, offset)
+		query = fmt.Sprintf("%s where %s ORDER BY mb_merchant_id ASC LIMIT %d, %s", selectMerchant, publicMerchant, (pageInt-1)*offsetInt, offset)
+	}
+	count, err := a.GetCountRows(ctx, " WHERE "+publicMerchant)
+	if err != nil {
+		return nil, 0, err
 	}
-	//fmt.Println(query)
-	count, _ := a.GetCountRows(ctx, "")
-	fmt.Println(count)
+	logging.LoggerFromContext(ctx).WithField("count", count).Debug("merchants matched")
 	res, err := a.fetch(ctx, query)
 	if err != nil {
 		return nil, 0, err
@@ -212,96 +234,234 @@ func (a *mysqlMerchantRepository) Fetch(ctx context.Context, page string, offset
 	return res, count, nil
 }
 
-func (a *mysqlMerchantRepository) FetchCategories(ctx context.Context) ([]*models.MbDiscoveryCategory, error) {
-	query := `SELECT mb_category_id, name, description, code, image FROM mb_merchant_category`
-	res, err := a.fetchCategories(ctx, query)
+// FetchChanges pages through the merchants in (updated_at, mb_merchant_id) order. Rows are expected to
+// carry updated_at, see migrations/0001_merchant_timestamps.sql.
+func (a *mysqlMerchantRepository) FetchChanges(ctx context.Context, since time.Time, afterID int64, limit int) ([]*models.Merchant, error) {
+	query := selectMerchant + ` WHERE updated_at > ? OR (updated_at = ? AND mb_merchant_id > ?) ORDER BY updated_at ASC, mb_merchant_id ASC LIMIT ?`
+
+	return a.fetch(ctx, query, since, since, afterID, limit)
+}
+
+func (a *mysqlMerchantRepository) FetchByStatus(ctx context.Context, status string, page string, offset string) ([]*models.Merchant, int64, error) {
+	query := selectMerchant + ` WHERE deleted_at is null AND status = ? ORDER BY mb_merchant_id ASC`
+	args := []interface{}{status}
+	if len(page) != 0 && len(offset) != 0 {
+		pageInt, err := strconv.Atoi(page)
+		if err != nil {
+			return nil, 0, errors.New("Page must be a number")
+		}
+		offsetInt, err := strconv.Atoi(offset)
+		if err != nil {
+			return nil, 0, errors.New("Offset must be a number")
+		}
+		if pageInt < 1 || offsetInt < 0 {
+			return nil, 0, errors.New("Could not enter a negative number")
+		}
+		query += ` LIMIT ?, ?`
+		args = append(args, (pageInt-1)*offsetInt, offsetInt)
+	}
+
+	list, err := a.fetch(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return res, nil
+	var count int64
+	err = a.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM mb_merchant WHERE deleted_at is null AND status = ?`, status).Scan(&count)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, 0, err
+	}
+	return list, count, nil
 }
 
-func (a *mysqlMerchantRepository) FetchArea(ctx context.Context) ([]*models.Area, error) {
-	query := `SELECT area_id, name, region_id, description, image FROM area`
-	res, err := a.fetchArea(ctx, query)
+func (a *mysqlMerchantRepository) UpdateStatus(ctx context.Context, t *models.MerchantStatusTransition) error {
+	tx, err := a.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
 	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
 
-	return res, nil
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE mb_merchant SET status = ?, version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND status = ? AND deleted_at is null`,
+		t.ToStatus, now, t.MerchantID, t.FromStatus)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// the status moved under us since the caller read it
+		err = models.ErrConflict
+		return err
+	}
+
+	res, err = tx.ExecContext(ctx, `INSERT INTO mb_merchant_status_log (mb_merchant_id, from_status, to_status, reason, actor, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		t.MerchantID, t.FromStatus, t.ToStatus, t.Reason, t.Actor, now)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if t.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if err = storeSnapshot(ctx, tx, t.MerchantID); err != nil {
+		return err
+	}
+	t.CreatedAt = now
+
+	return tx.Commit()
 }
 
-func (a *mysqlMerchantRepository) GetByID(ctx context.Context, id int64) (res *models.Merchant, err error) {
-	query := fmt.Sprintf("SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant WHERE is_deleted is null AND mb_merchant_id = %d", id)
+func (a *mysqlMerchantRepository) FetchStatusTransitions(ctx context.Context, merchantID int64) ([]*models.MerchantStatusTransition, error) {
+	query := `SELECT id, mb_merchant_id, from_status, to_status, reason, actor, created_at FROM mb_merchant_status_log WHERE mb_merchant_id = ? ORDER BY id ASC`
 
-	list, err := a.fetchDetail(ctx, query, id)
+	rows, err := a.DB.QueryContext(ctx, query, merchantID)
 	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
 		return nil, err
 	}
 
-	if len(list) > 0 {
-		res = list[0]
-	} else {
-		return nil, models.ErrNotFound
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.MerchantStatusTransition, 0)
+
+	for rows.Next() {
+		t := new(models.MerchantStatusTransition)
+		err = rows.Scan(
+			&t.ID,
+			&t.MerchantID,
+			&t.FromStatus,
+			&t.ToStatus,
+			&t.Reason,
+			&t.Actor,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		results = append(results, t)
 	}
 
-	return
+	return results, nil
 }
 
-func checkCount(rows *sql.Rows) (count int64) {
+func (a *mysqlMerchantRepository) FetchOwners(ctx context.Context, id int64) ([]*models.MerchantOwner, error) {
+	query := `SELECT mb_merchant_id, subject, created_at FROM mb_merchant_owner WHERE mb_merchant_id = ? ORDER BY created_at ASC`
+
+	rows, err := a.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.MerchantOwner, 0)
+
 	for rows.Next() {
-		err := rows.Scan(&count)
-		checkErr(err)
+		t := new(models.MerchantOwner)
+		err = rows.Scan(
+			&t.MerchantID,
+			&t.Subject,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		results = append(results, t)
 	}
-	return count
+
+	return results, nil
 }
 
-func checkErr(err error) {
+func (a *mysqlMerchantRepository) IsOwner(ctx context.Context, id int64, subject string) (bool, error) {
+	var count int64
+	err := a.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM mb_merchant_owner WHERE mb_merchant_id = ? AND subject = ?`, id, subject).Scan(&count)
 	if err != nil {
-		panic(err)
+		logging.LoggerFromContext(ctx).Error(err)
+		return false, err
 	}
+	return count > 0, nil
 }
 
-func (a *mysqlMerchantRepository) GetCountRows(ctx context.Context, clause string) (int64, error) {
-	query := fmt.Sprintf("SELECT COUNT(*) as count FROM mb_merchant%s", clause)
-	fmt.Println(query)
-	rows, err := a.DB.QueryContext(ctx, query)
-	count := checkCount(rows)
+func (a *mysqlMerchantRepository) StoreOwner(ctx context.Context, o *models.MerchantOwner) error {
+	o.CreatedAt = time.Now()
+	_, err := a.DB.ExecContext(ctx, `INSERT INTO mb_merchant_owner (mb_merchant_id, subject, created_at) VALUES (?, ?, ?)`, o.MerchantID, o.Subject, o.CreatedAt)
+	if myErr, ok := err.(*mysql.MySQLError); ok && myErr.Number == mysqlDuplicateEntry {
+		return models.ErrConflict
+	}
 	if err != nil {
-		logrus.Error(err)
-		return 0, err
+		logging.LoggerFromContext(ctx).Error(err)
 	}
-	return count, nil
+	return err
 }
 
-func (a *mysqlMerchantRepository) GetImagesByID(ctx context.Context, id int64) ([]*models.Image, error) {
-	query := fmt.Sprintf("SELECT id, mb_merchant_id, image FROM mb_merchant_image WHERE mb_merchant_id = %d", id)
+func (a *mysqlMerchantRepository) DeleteOwner(ctx context.Context, id int64, subject string) error {
+	res, err := a.DB.ExecContext(ctx, `DELETE FROM mb_merchant_owner WHERE mb_merchant_id = ? AND subject = ?`, id, subject)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
+}
 
-	rows, err := a.DB.QueryContext(ctx, query)
+const selectClaim = `SELECT id, mb_merchant_id, subject, status, code_hash, attempts, expires_at, reason, created_at, updated_at FROM mb_merchant_claim`
+
+func (a *mysqlMerchantRepository) fetchClaims(ctx context.Context, query string, args ...interface{}) ([]*models.MerchantClaim, error) {
+	rows, err := a.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		logrus.Error(err)
+		logging.LoggerFromContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 		}
 	}()
 
-	results := make([]*models.Image, 0)
+	results := make([]*models.MerchantClaim, 0)
 
 	for rows.Next() {
-		t := new(models.Image)
+		t := new(models.MerchantClaim)
 		err = rows.Scan(
 			&t.ID,
 			&t.MerchantID,
-			&t.Image,
+			&t.Subject,
+			&t.Status,
+			&t.CodeHash,
+			&t.Attempts,
+			&t.ExpiresAt,
+			&t.Reason,
+			&t.CreatedAt,
+			&t.UpdatedAt,
 		)
 		if err != nil {
-			logrus.Error(err)
+			logging.LoggerFromContext(ctx).Error(err)
 			return nil, err
 		}
 		results = append(results, t)
@@ -310,61 +470,1240 @@ func (a *mysqlMerchantRepository) GetImagesByID(ctx context.Context, id int64) (
 	return results, nil
 }
 
-func (a *mysqlMerchantRepository) FilterByMulti(ctx context.Context, clause string, page string, offset string) ([]*models.Merchant, int64, error) {
-	query := fmt.Sprintf("SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant WHERE is_deleted is null AND %s", clause)
-	queryCount := fmt.Sprintf(" WHERE is_deleted is null AND %s", clause)
-	if len(clause) == 0 {
-		query = fmt.Sprintf("SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant WHERE is_deleted is null")
-		queryCount = fmt.Sprintf(" WHERE is_deleted is null")
+func (a *mysqlMerchantRepository) FetchClaims(ctx context.Context, status string) ([]*models.MerchantClaim, error) {
+	return a.fetchClaims(ctx, selectClaim+` WHERE status = ? ORDER BY id ASC`, status)
+}
+
+func (a *mysqlMerchantRepository) FetchMerchantClaims(ctx context.Context, id int64) ([]*models.MerchantClaim, error) {
+	return a.fetchClaims(ctx, selectClaim+` WHERE mb_merchant_id = ? ORDER BY id ASC`, id)
+}
+
+// CountSubjectClaims returns how many claims subject opened since then, on any merchant
+func (a *mysqlMerchantRepository) CountSubjectClaims(ctx context.Context, subject string, since time.Time) (int64, error) {
+	var count int64
+	err := a.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM mb_merchant_claim WHERE subject = ? AND created_at >= ?`, subject, since).Scan(&count)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return 0, err
 	}
+	return count, nil
+}
 
-	if len(page) != 0 && len(offset) != 0 {
-		pageInt, err := strconv.Atoi(page)
+func (a *mysqlMerchantRepository) GetClaim(ctx context.Context, id int64) (*models.MerchantClaim, error) {
+	list, err := a.fetchClaims(ctx, selectClaim+` WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (a *mysqlMerchantRepository) StoreClaim(ctx context.Context, c *models.MerchantClaim) error {
+	query := `INSERT INTO mb_merchant_claim (mb_merchant_id, subject, status, code_hash, attempts, expires_at, reason, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now()
+	res, err := a.DB.ExecContext(ctx, query, c.MerchantID, c.Subject, c.Status, c.CodeHash, c.Attempts, c.ExpiresAt, c.Reason, now, now)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	c.CreatedAt, c.UpdatedAt = now, now
+	c.ID, err = res.LastInsertId()
+	return err
+}
+
+// UpdateClaim saves the state of a claim that was still in status from, so two reviewers cannot
+// both act on the same claim
+func (a *mysqlMerchantRepository) UpdateClaim(ctx context.Context, c *models.MerchantClaim, from string) error {
+	query := `UPDATE mb_merchant_claim SET status = ?, attempts = ?, reason = ?, updated_at = ? WHERE id = ? AND status = ?`
+
+	now := time.Now()
+	res, err := a.DB.ExecContext(ctx, query, c.Status, c.Attempts, c.Reason, now, c.ID, from)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if err := checkAffected(res); err != nil {
+		return models.ErrConflict
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+// ApplyClaim saves the new status of a claim that was still in status from and, in the same
+// transaction, makes the claimant an owner when the claim is approved or takes the ownership back
+// when it leaves approved. An exclusive approval locks the merchant and fails with ErrAlreadyOwned
+// when it has an owner, so two claimants cannot both become the first one.
+func (a *mysqlMerchantRepository) ApplyClaim(ctx context.Context, c *models.MerchantClaim, from string, exclusive bool) error {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
 		if err != nil {
-			return nil, 0, errors.New("Page must be a number")
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
 		}
-		offsetInt, err := strconv.Atoi(offset)
-		if err != nil {
-			return nil, 0, errors.New("Offset must be a number")
+	}()
+
+	if exclusive && c.Status == models.ClaimApproved {
+		var id, owners int64
+		if err = tx.QueryRowContext(ctx, `SELECT mb_merchant_id FROM mb_merchant WHERE mb_merchant_id = ? FOR UPDATE`, c.MerchantID).Scan(&id); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
 		}
-		if pageInt < 0 || offsetInt < 0 {
-			return nil, 0, errors.New("Could not enter a negative number")
+		if err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM mb_merchant_owner WHERE mb_merchant_id = ?`, c.MerchantID).Scan(&owners); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
 		}
-		query = fmt.Sprintf("SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant WHERE is_deleted is null AND %s ORDER BY mb_merchant_id ASC LIMIT %d, %s", clause, (pageInt-1)*offsetInt, offset)
-		if len(clause) == 0 {
-			query = fmt.Sprintf("SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant WHERE is_deleted is null ORDER BY mb_merchant_id ASC LIMIT %d, %s", (pageInt-1)*offsetInt, offset)
+		if owners > 0 {
+			err = models.ErrAlreadyOwned
+			return err
 		}
 	}
 
-	list, err := a.fetch(ctx, query)
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE mb_merchant_claim SET status = ?, attempts = ?, reason = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		c.Status, c.Attempts, c.Reason, now, c.ID, from)
 	if err != nil {
-		return nil, 0, err
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
 	}
-	count, _ := a.GetCountRows(ctx, queryCount)
-	fmt.Println(count)
-	return list, count, nil
+	if err = checkAffected(res); err != nil {
+		err = models.ErrConflict
+		return err
+	}
+
+	switch {
+	case c.Status == models.ClaimApproved:
+		// the claimant may have been made an owner by an admin meanwhile, which is as good
+		_, err = tx.ExecContext(ctx, `INSERT IGNORE INTO mb_merchant_owner (mb_merchant_id, subject, created_at) VALUES (?, ?, ?)`,
+			c.MerchantID, c.Subject, now)
+	case from == models.ClaimApproved:
+		_, err = tx.ExecContext(ctx, `DELETE FROM mb_merchant_owner WHERE mb_merchant_id = ? AND subject = ?`, c.MerchantID, c.Subject)
+	}
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	c.UpdatedAt = now
+	return nil
 }
 
-func (a *mysqlMerchantRepository) SearchByKeyword(ctx context.Context, keyword string) ([]*models.Merchant, int64, error) {
-	query := `SELECT mb_merchant_id, name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook FROM mb_merchant WHERE is_deleted is null AND name like ?`
+func (a *mysqlMerchantRepository) FetchCategories(ctx context.Context) ([]*models.MbDiscoveryCategory, error) {
+	query := `SELECT mb_category_id, name, description, code, image FROM mb_merchant_category`
+	res, err := a.fetchCategories(ctx, query)
+	if err != nil {
+		return nil, err
+	}
 
-	list, err := a.fetch(ctx, query, "%"+keyword+"%")
+	return res, nil
+}
+
+func (a *mysqlMerchantRepository) GetCategoryByID(ctx context.Context, id int64) (*models.MbDiscoveryCategory, error) {
+	query := `SELECT mb_category_id, name, description, code, image FROM mb_merchant_category WHERE mb_category_id = ?`
+	list, err := a.fetchCategories(ctx, query, id)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	count, _ := a.GetCountRows(ctx, "")
-	fmt.Println(count)
-	return list, count, nil
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return list[0], nil
 }
 
-func (a *mysqlMerchantRepository) Update(ctx context.Context, m *models.Merchant) error {
-	return nil
+func (a *mysqlMerchantRepository) StoreCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	query := `INSERT INTO mb_merchant_category (name, description, code, image) VALUES (?, ?, ?, ?)`
+	res, err := a.DB.ExecContext(ctx, query, m.Name, m.Description, m.Code, m.Image)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	m.ID, err = res.LastInsertId()
+	return err
 }
 
-func (a *mysqlMerchantRepository) Store(ctx context.Context, m *models.Merchant) error {
-	return nil
+func (a *mysqlMerchantRepository) UpdateCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	query := `UPDATE mb_merchant_category SET name = ?, description = ?, code = ?, image = ? WHERE mb_category_id = ?`
+	res, err := a.DB.ExecContext(ctx, query, m.Name, m.Description, m.Code, m.Image, m.ID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
 }
 
-func (a *mysqlMerchantRepository) Delete(ctx context.Context, id int64) error {
-	return nil
+func (a *mysqlMerchantRepository) DeleteCategory(ctx context.Context, id int64) error {
+	res, err := a.DB.ExecContext(ctx, `DELETE FROM mb_merchant_category WHERE mb_category_id = ?`, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (a *mysqlMerchantRepository) FetchArea(ctx context.Context) ([]*models.Area, error) {
+	query := `SELECT area_id, name, region_id, description, image, boundary FROM area`
+	res, err := a.fetchArea(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *mysqlMerchantRepository) GetAreaByID(ctx context.Context, id int64) (*models.Area, error) {
+	query := `SELECT area_id, name, region_id, description, image, boundary FROM area WHERE area_id = ?`
+	list, err := a.fetchArea(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return list[0], nil
+}
+
+// marshalBoundary encodes an area boundary for its JSON column, NULL when the area has none
+func marshalBoundary(p models.Polygon) ([]byte, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (a *mysqlMerchantRepository) StoreArea(ctx context.Context, m *models.Area) error {
+	boundary, err := marshalBoundary(m.Boundary)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO area (name, region_id, description, image, boundary) VALUES (?, ?, ?, ?, ?)`
+	res, err := a.DB.ExecContext(ctx, query, m.Name, m.RegionID, m.Description, m.Image, boundary)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	m.ID, err = res.LastInsertId()
+	return err
+}
+
+func (a *mysqlMerchantRepository) UpdateArea(ctx context.Context, m *models.Area) error {
+	boundary, err := marshalBoundary(m.Boundary)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE area SET name = ?, region_id = ?, description = ?, image = ?, boundary = ? WHERE area_id = ?`
+	res, err := a.DB.ExecContext(ctx, query, m.Name, m.RegionID, m.Description, m.Image, boundary, m.ID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (a *mysqlMerchantRepository) DeleteArea(ctx context.Context, id int64) error {
+	res, err := a.DB.ExecContext(ctx, `DELETE FROM area WHERE area_id = ?`, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (a *mysqlMerchantRepository) GetByID(ctx context.Context, id int64) (res *models.Merchant, err error) {
+	query := fmt.Sprintf("%s WHERE deleted_at is null AND mb_merchant_id = %d", selectMerchant, id)
+
+	list, err := a.fetchDetail(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return nil, models.ErrNotFound
+	}
+
+	return
+}
+
+func (a *mysqlMerchantRepository) FetchActive(ctx context.Context) ([]*models.Merchant, error) {
+	return a.fetch(ctx, selectMerchant+` WHERE deleted_at is null ORDER BY mb_merchant_id ASC`)
+}
+
+func (a *mysqlMerchantRepository) GetMergedInto(ctx context.Context, id int64) (int64, error) {
+	var survivorID int64
+	err := a.DB.QueryRowContext(ctx, `SELECT merged_into FROM mb_merchant WHERE mb_merchant_id = ? AND merged_into IS NOT NULL`, id).Scan(&survivorID)
+	if err == sql.ErrNoRows {
+		return 0, models.ErrNotFound
+	}
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return 0, err
+	}
+	return survivorID, nil
+}
+
+func (a *mysqlMerchantRepository) Merge(ctx context.Context, survivorID int64, mergedID int64) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE mb_merchant SET deleted_at = ?, merged_into = ?, version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND deleted_at is null`,
+		now, survivorID, now, mergedID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+	res, err = tx.ExecContext(ctx, `UPDATE mb_merchant SET version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND deleted_at is null`, now, survivorID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE mb_merchant_image SET mb_merchant_id = ? WHERE mb_merchant_id = ?`, []interface{}{survivorID, mergedID}},
+		// the owners of the merged merchant keep their shop; those of both keep a single row
+		{`INSERT IGNORE INTO mb_merchant_owner (mb_merchant_id, subject, created_at) SELECT ?, subject, ? FROM mb_merchant_owner WHERE mb_merchant_id = ?`,
+			[]interface{}{survivorID, now, mergedID}},
+		{`DELETE FROM mb_merchant_owner WHERE mb_merchant_id = ?`, []interface{}{mergedID}},
+		// earlier merges into the merged merchant now redirect straight to the survivor
+		{`UPDATE mb_merchant SET merged_into = ? WHERE merged_into = ?`, []interface{}{survivorID, mergedID}},
+		{`UPDATE mb_merchant_duplicate SET status = ? WHERE status = ? AND ((mb_merchant_id = ? AND duplicate_id = ?) OR (mb_merchant_id = ? AND duplicate_id = ?))`,
+			[]interface{}{models.DuplicateMerged, models.DuplicatePending, survivorID, mergedID, mergedID, survivorID}},
+		{`DELETE FROM mb_merchant_duplicate WHERE status = ? AND (mb_merchant_id = ? OR duplicate_id = ?)`, []interface{}{models.DuplicatePending, mergedID, mergedID}},
+	}
+	for _, st := range statements {
+		if _, err = tx.ExecContext(ctx, st.query, st.args...); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+	}
+	if err = storeSnapshot(ctx, tx, survivorID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *mysqlMerchantRepository) ReplaceDuplicateCandidates(ctx context.Context, list []*models.DuplicateCandidate) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM mb_merchant_duplicate WHERE status = ?`, models.DuplicatePending); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	// pairs already dismissed or merged keep their row, so the unique pair key skips them
+	query := `INSERT IGNORE INTO mb_merchant_duplicate (mb_merchant_id, duplicate_id, score, name_score, phone_match, distance_meters, status, created_at) VALUES `
+	now := time.Now()
+	for start := 0; start < len(list); start += duplicateInsertBatch {
+		batch := list[start:]
+		if len(batch) > duplicateInsertBatch {
+			batch = batch[:duplicateInsertBatch]
+		}
+		args := make([]interface{}, 0, len(batch)*8)
+		for _, d := range batch {
+			args = append(args, d.MerchantID, d.DuplicateID, d.Score, d.NameScore, d.PhoneMatch, d.DistanceMeters, models.DuplicatePending, now)
+		}
+		values := strings.Repeat(", (?, ?, ?, ?, ?, ?, ?, ?)", len(batch))[2:]
+		if _, err = tx.ExecContext(ctx, query+values, args...); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (a *mysqlMerchantRepository) FetchDuplicateCandidates(ctx context.Context, status string, minScore float64) ([]*models.DuplicateCandidate, error) {
+	query := `SELECT id, mb_merchant_id, duplicate_id, score, name_score, phone_match, distance_meters, status, created_at FROM mb_merchant_duplicate WHERE status = ? AND score >= ? ORDER BY score DESC`
+
+	rows, err := a.DB.QueryContext(ctx, query, status, minScore)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.DuplicateCandidate, 0)
+
+	for rows.Next() {
+		t := new(models.DuplicateCandidate)
+		err = rows.Scan(
+			&t.ID,
+			&t.MerchantID,
+			&t.DuplicateID,
+			&t.Score,
+			&t.NameScore,
+			&t.PhoneMatch,
+			&t.DistanceMeters,
+			&t.Status,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		results = append(results, t)
+	}
+
+	return results, nil
+}
+
+func (a *mysqlMerchantRepository) UpdateDuplicateStatus(ctx context.Context, id int64, status string) error {
+	res, err := a.DB.ExecContext(ctx, `UPDATE mb_merchant_duplicate SET status = ? WHERE id = ?`, status, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (a *mysqlMerchantRepository) ResolveSlug(ctx context.Context, slug string) (int64, error) {
+	var id int64
+	err := a.DB.QueryRowContext(ctx, `SELECT mb_merchant_id FROM mb_merchant_slug WHERE slug = ?`, slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, models.ErrNotFound
+	}
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (a *mysqlMerchantRepository) SetSlug(ctx context.Context, id int64, slug string) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	// every slug a merchant ever had stays in mb_merchant_slug so old URLs keep resolving
+	_, err = tx.ExecContext(ctx, `INSERT INTO mb_merchant_slug (slug, mb_merchant_id, created_at) VALUES (?, ?, ?)`, slug, id, time.Now())
+	if myErr, ok := err.(*mysql.MySQLError); ok && myErr.Number == mysqlDuplicateEntry {
+		var owner int64
+		if err = tx.QueryRowContext(ctx, `SELECT mb_merchant_id FROM mb_merchant_slug WHERE slug = ?`, slug).Scan(&owner); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+		if owner != id {
+			err = models.ErrConflict
+			return err
+		}
+	} else if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE mb_merchant SET slug = ?, version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND deleted_at is null`, slug, time.Now(), id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func checkCount(rows *sql.Rows) (count int64) {
+	for rows.Next() {
+		err := rows.Scan(&count)
+		checkErr(err)
+	}
+	return count
+}
+
+// checkAffected reports ErrNotFound when a write matched no row
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func checkErr(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (a *mysqlMerchantRepository) GetCountRows(ctx context.Context, clause string) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) as count FROM mb_merchant%s", clause)
+	logging.LoggerFromContext(ctx).WithField("query", query).Debug("counting merchants")
+	rows, err := a.DB.QueryContext(ctx, query)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return 0, err
+	}
+	defer rows.Close()
+	return checkCount(rows), nil
+}
+
+func (a *mysqlMerchantRepository) GetImagesByID(ctx context.Context, id int64) ([]*models.Image, error) {
+	query := fmt.Sprintf("SELECT id, mb_merchant_id, image FROM mb_merchant_image WHERE mb_merchant_id = %d", id)
+
+	rows, err := a.DB.QueryContext(ctx, query)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.Image, 0)
+
+	for rows.Next() {
+		t := new(models.Image)
+		err = rows.Scan(
+			&t.ID,
+			&t.MerchantID,
+			&t.Image,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		results = append(results, t)
+	}
+
+	return results, nil
+}
+
+func (a *mysqlMerchantRepository) StoreImage(ctx context.Context, img *models.Image) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	// images are part of the merchant representation, so adding one moves its version
+	res, err := tx.ExecContext(ctx, `UPDATE mb_merchant SET version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND deleted_at is null`, time.Now(), img.MerchantID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+	res, err = tx.ExecContext(ctx, `INSERT INTO mb_merchant_image (mb_merchant_id, image) VALUES (?, ?)`, img.MerchantID, img.Image)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if img.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if err = storeSnapshot(ctx, tx, img.MerchantID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *mysqlMerchantRepository) ReplaceImages(ctx context.Context, id int64, images []*models.Image) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	if err = replaceImages(ctx, tx, id, images); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceImages swaps the gallery of a merchant; run it inside a transaction
+func replaceImages(ctx context.Context, db execer, id int64, images []*models.Image) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM mb_merchant_image WHERE mb_merchant_id = ?`, id); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	for _, img := range images {
+		res, err := db.ExecContext(ctx, `INSERT INTO mb_merchant_image (mb_merchant_id, image) VALUES (?, ?)`, id, img.Image)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+		if img.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		img.MerchantID = id
+	}
+
+	return nil
+}
+
+func (a *mysqlMerchantRepository) fetchRevisions(ctx context.Context, query string, args ...interface{}) ([]*models.MerchantRevision, error) {
+	rows, err := a.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.MerchantRevision, 0)
+
+	for rows.Next() {
+		t := new(models.MerchantRevision)
+		var snapshot []byte
+		err = rows.Scan(
+			&t.ID,
+			&t.MerchantID,
+			&t.Revision,
+			&snapshot,
+			&t.Actor,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		if err = json.Unmarshal(snapshot, &t.Snapshot); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		results = append(results, t)
+	}
+
+	return results, nil
+}
+
+func (a *mysqlMerchantRepository) StoreRevision(ctx context.Context, r *models.MerchantRevision) error {
+	return storeRevision(ctx, a.DB, r)
+}
+
+func storeRevision(ctx context.Context, db execer, r *models.MerchantRevision) error {
+	// the revision number is allocated in the same statement so concurrent writers cannot share one
+	query := `INSERT INTO mb_merchant_revision (mb_merchant_id, revision, snapshot, actor, created_at) SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ? FROM mb_merchant_revision WHERE mb_merchant_id = ?`
+
+	snapshot, err := json.Marshal(r.Snapshot)
+	if err != nil {
+		return err
+	}
+	r.CreatedAt = time.Now()
+	res, err := db.ExecContext(ctx, query, r.MerchantID, snapshot, r.Actor, r.CreatedAt, r.MerchantID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if r.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	return db.QueryRowContext(ctx, `SELECT revision FROM mb_merchant_revision WHERE id = ?`, r.ID).Scan(&r.Revision)
+}
+
+// storeSnapshot reads merchant id and its images back inside tx and stores them as a new revision by
+// the actor of ctx, so a write and its revision commit or fail together
+func storeSnapshot(ctx context.Context, tx *tracedTx, id int64) error {
+	rows, err := tx.QueryContext(ctx, selectMerchant+` WHERE mb_merchant_id = ?`, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	m := new(models.Merchant)
+	found := rows.Next()
+	if found {
+		err = scanMerchant(rows, m)
+	}
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if !found {
+		return models.ErrNotFound
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT id, mb_merchant_id, image FROM mb_merchant_image WHERE mb_merchant_id = ? ORDER BY id ASC`, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	m.Images = make([]*models.Image, 0)
+	for rows.Next() {
+		img := new(models.Image)
+		if err = rows.Scan(&img.ID, &img.MerchantID, &img.Image); err != nil {
+			break
+		}
+		m.Images = append(m.Images, img)
+	}
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+
+	return storeRevision(ctx, tx, &models.MerchantRevision{MerchantID: id, Snapshot: m, Actor: models.ActorFromContext(ctx)})
+}
+
+func (a *mysqlMerchantRepository) GetRevision(ctx context.Context, id int64, revision int64) (*models.MerchantRevision, error) {
+	query := `SELECT id, mb_merchant_id, revision, snapshot, actor, created_at FROM mb_merchant_revision WHERE mb_merchant_id = ? AND revision = ?`
+	list, err := a.fetchRevisions(ctx, query, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return list[0], nil
+}
+
+func (a *mysqlMerchantRepository) FetchRevisions(ctx context.Context, id int64) ([]*models.MerchantRevision, error) {
+	query := `SELECT id, mb_merchant_id, revision, snapshot, actor, created_at FROM mb_merchant_revision WHERE mb_merchant_id = ? ORDER BY revision DESC`
+
+	return a.fetchRevisions(ctx, query, id)
+}
+
+// filterColumns are the mb_merchant columns a MerchantFilter may match on, true for the numeric ones
+var filterColumns = map[string]bool{
+	"mb_category_id": true,
+	"area_id":        true,
+	"delivery":       true,
+	"slug":           false,
+}
+
+// validFilterValues tells whether values are something column can hold: none empty, and integers
+// for a numeric column
+func validFilterValues(column string, values []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if len(strings.TrimSpace(v)) == 0 {
+			return false
+		}
+		if _, err := strconv.ParseInt(v, 10, 64); filterColumns[column] && err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// filterWhere turns a filter into conditions on public merchants and their arguments. Fields outside
+// filterColumns, such as cache-busting params, are ignored; an invalid value of a known column is an
+// ErrBadParamInput
+func filterWhere(filter *models.MerchantFilter) (string, []interface{}, error) {
+	conditions := []string{publicMerchant}
+	args := []interface{}{}
+	if filter == nil {
+		return publicMerchant, args, nil
+	}
+	if len(filter.Keyword) != 0 {
+		conditions = append(conditions, `name LIKE CONCAT('%', ?, '%')`)
+		args = append(args, filter.Keyword)
+	}
+	columns := make([]string, 0, len(filter.Fields))
+	for column := range filter.Fields {
+		columns = append(columns, column)
+	}
+	// a stable order keeps the statements the same for the same filter
+	sort.Strings(columns)
+	for _, column := range columns {
+		values := filter.Fields[column]
+		if _, ok := filterColumns[column]; !ok {
+			continue
+		}
+		if !validFilterValues(column, values) {
+			return "", nil, models.ErrBadParamInput
+		}
+		conditions = append(conditions, column+" IN (?"+strings.Repeat(", ?", len(values)-1)+")")
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+func (a *mysqlMerchantRepository) FilterByMulti(ctx context.Context, filter *models.MerchantFilter, page string, offset string) ([]*models.Merchant, int64, error) {
+	where, args, err := filterWhere(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := selectMerchant + " WHERE " + where + " ORDER BY mb_merchant_id ASC"
+	queryArgs := args
+
+	if len(page) != 0 && len(offset) != 0 {
+		pageInt, err := strconv.Atoi(page)
+		if err != nil {
+			return nil, 0, errors.New("Page must be a number")
+		}
+		offsetInt, err := strconv.Atoi(offset)
+		if err != nil {
+			return nil, 0, errors.New("Offset must be a number")
+		}
+		if pageInt < 0 || offsetInt < 0 {
+			return nil, 0, errors.New("Could not enter a negative number")
+		}
+		query += " LIMIT ?, ?"
+		queryArgs = append(append([]interface{}{}, args...), (pageInt-1)*offsetInt, offsetInt)
+	}
+
+	list, err := a.fetch(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	var count int64
+	if err := a.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM mb_merchant WHERE "+where, args...).Scan(&count); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+	}
+	logging.LoggerFromContext(ctx).WithField("count", count).Debug("merchants matched")
+	return list, count, nil
+}
+
+func (a *mysqlMerchantRepository) StreamLocated(ctx context.Context, filter *models.MerchantFilter, bbox *models.BoundingBox, fn func(*models.Merchant) error) error {
+	where, args, err := filterWhere(filter)
+	if err != nil {
+		return err
+	}
+	query := selectMerchant + ` WHERE ` + where + ` AND latitude IS NOT NULL AND longitude IS NOT NULL`
+	if bbox != nil {
+		query += ` AND longitude BETWEEN ? AND ? AND latitude BETWEEN ? AND ?`
+		args = append(args, bbox.MinLng, bbox.MaxLng, bbox.MinLat, bbox.MaxLat)
+	}
+	query += ` ORDER BY mb_merchant_id ASC`
+
+	rows, err := a.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	for rows.Next() {
+		t := new(models.Merchant)
+		if err = scanMerchant(rows, t); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+		if err = fn(t); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamApproved calls fn for each public merchant of a page of limit rows, in mb_merchant_id order
+func (a *mysqlMerchantRepository) StreamApproved(ctx context.Context, page int, limit int, fn func(*models.Merchant) error) error {
+	if page < 1 || limit < 0 {
+		return models.ErrBadParamInput
+	}
+	query := selectMerchant + ` WHERE ` + publicMerchant + ` ORDER BY mb_merchant_id ASC LIMIT ?, ?`
+
+	rows, err := a.DB.QueryContext(ctx, query, (page-1)*limit, limit)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	for rows.Next() {
+		t := new(models.Merchant)
+		if err = scanMerchant(rows, t); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+		if err = fn(t); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (a *mysqlMerchantRepository) fetchDeliveryZones(ctx context.Context, query string, args ...interface{}) ([]*models.DeliveryZone, error) {
+	rows, err := a.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+
+	results := make([]*models.DeliveryZone, 0)
+
+	for rows.Next() {
+		t := new(models.DeliveryZone)
+		var polygon []byte
+		err = rows.Scan(
+			&t.ID,
+			&t.MerchantID,
+			&t.Name,
+			&polygon,
+			&t.RadiusMeters,
+		)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return nil, err
+		}
+		if len(polygon) != 0 {
+			if err = json.Unmarshal(polygon, &t.Polygon); err != nil {
+				logging.LoggerFromContext(ctx).Error(err)
+				return nil, err
+			}
+		}
+		results = append(results, t)
+	}
+
+	return results, nil
+}
+
+func (a *mysqlMerchantRepository) FetchDeliveryZones(ctx context.Context, id int64) ([]*models.DeliveryZone, error) {
+	query := `SELECT id, mb_merchant_id, name, polygon, radius_meters FROM mb_merchant_delivery_zone WHERE mb_merchant_id = ? ORDER BY id ASC`
+
+	return a.fetchDeliveryZones(ctx, query, id)
+}
+
+func (a *mysqlMerchantRepository) FetchAllDeliveryZones(ctx context.Context) ([]*models.DeliveryZone, error) {
+	query := `SELECT z.id, z.mb_merchant_id, z.name, z.polygon, z.radius_meters FROM mb_merchant_delivery_zone z JOIN mb_merchant m ON m.mb_merchant_id = z.mb_merchant_id WHERE m.deleted_at is null AND m.status = 'approved'`
+
+	return a.fetchDeliveryZones(ctx, query)
+}
+
+func (a *mysqlMerchantRepository) ReplaceDeliveryZones(ctx context.Context, id int64, zones []*models.DeliveryZone) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM mb_merchant_delivery_zone WHERE mb_merchant_id = ?`, id); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	for _, z := range zones {
+		var polygon []byte
+		if len(z.Polygon) != 0 {
+			if polygon, err = json.Marshal(z.Polygon); err != nil {
+				return err
+			}
+		}
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, `INSERT INTO mb_merchant_delivery_zone (mb_merchant_id, name, polygon, radius_meters) VALUES (?, ?, ?, ?)`,
+			id, z.Name, polygon, z.RadiusMeters)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+		if z.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		z.MerchantID = id
+	}
+
+	return tx.Commit()
+}
+
+func (a *mysqlMerchantRepository) GetDeliveryPricing(ctx context.Context, id int64) (*models.DeliveryPricing, error) {
+	query := `SELECT mb_merchant_id, base_fee, per_km_fee, free_over_subtotal, min_order, prep_minutes FROM mb_merchant_delivery_pricing WHERE mb_merchant_id = ?`
+
+	t := new(models.DeliveryPricing)
+	err := a.DB.QueryRowContext(ctx, query, id).Scan(
+		&t.MerchantID,
+		&t.BaseFee,
+		&t.PerKmFee,
+		&t.FreeOverSubtotal,
+		&t.MinOrder,
+		&t.PrepMinutes,
+	)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+	return t, nil
+}
+
+func (a *mysqlMerchantRepository) StoreDeliveryPricing(ctx context.Context, p *models.DeliveryPricing) error {
+	query := `INSERT INTO mb_merchant_delivery_pricing (mb_merchant_id, base_fee, per_km_fee, free_over_subtotal, min_order, prep_minutes) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE base_fee = VALUES(base_fee), per_km_fee = VALUES(per_km_fee), free_over_subtotal = VALUES(free_over_subtotal), min_order = VALUES(min_order), prep_minutes = VALUES(prep_minutes)`
+
+	_, err := a.DB.ExecContext(ctx, query, p.MerchantID, p.BaseFee, p.PerKmFee, p.FreeOverSubtotal, p.MinOrder, p.PrepMinutes)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+	}
+	return err
+}
+
+func (a *mysqlMerchantRepository) SearchByKeyword(ctx context.Context, keyword string) ([]*models.Merchant, int64, error) {
+	where := publicMerchant + ` AND name like ?`
+	pattern := "%" + keyword + "%"
+
+	list, err := a.fetch(ctx, selectMerchant+" WHERE "+where, pattern)
+	if err != nil {
+		return nil, 0, err
+	}
+	var count int64
+	if err = a.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM mb_merchant WHERE "+where, pattern).Scan(&count); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil, 0, err
+	}
+	logging.LoggerFromContext(ctx).WithField("count", count).Debug("merchants matched")
+	return list, count, nil
+}
+
+// Update writes m if its version is still the stored one and stores the result as a new revision in
+// the same transaction
+func (a *mysqlMerchantRepository) Update(ctx context.Context, m *models.Merchant) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	if err = updateMerchant(ctx, tx, m); err != nil {
+		return err
+	}
+	if err = storeSnapshot(ctx, tx, m.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func updateMerchant(ctx context.Context, db execer, m *models.Merchant) error {
+	query := `UPDATE mb_merchant SET name = ?, address = ?, latitude = ?, longitude = ?, phone = ?, description = ?, mb_category_id = ?, area_id = ?, image = ?, delivery = ?, time_start = ?, time_end = ?, facebook = ?, version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND version = ? AND deleted_at is null`
+
+	now := time.Now()
+	res, err := db.ExecContext(ctx, query, m.Name, m.Address, m.Latitude, m.Longitude, m.Phone, m.Description,
+		m.MbCategoryID, m.AreaID, m.Image, m.Delivery, m.TimeStart, m.TimeEnd, m.Facebook, now, m.ID, m.Version)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	if err := checkAffected(res); err != nil {
+		// tell a missing merchant apart from one whose version moved on
+		var version int64
+		err = db.QueryRowContext(ctx, `SELECT version FROM mb_merchant WHERE mb_merchant_id = ? AND deleted_at is null`, m.ID).Scan(&version)
+		if err == sql.ErrNoRows {
+			return models.ErrNotFound
+		}
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+		return models.ErrConflict
+	}
+	m.Version++
+	m.UpdatedAt = null.TimeFrom(now)
+
+	return nil
+}
+
+// Revert writes back the fields and gallery of m and stores r, whose snapshot is m as written, in one
+// transaction, so a failed revert leaves neither a half restored merchant nor a revision for it
+func (a *mysqlMerchantRepository) Revert(ctx context.Context, m *models.Merchant, r *models.MerchantRevision) (err error) {
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	if err = updateMerchant(ctx, tx, m); err != nil {
+		return err
+	}
+	if err = replaceImages(ctx, tx, m.ID, m.Images); err != nil {
+		return err
+	}
+	r.MerchantID = m.ID
+	r.Snapshot = m
+	if err = storeRevision(ctx, tx, r); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Store inserts a merchant and, when owner is not empty, records owner as its owner in the same
+// transaction
+func (a *mysqlMerchantRepository) Store(ctx context.Context, m *models.Merchant, owner string) (err error) {
+	query := `INSERT INTO mb_merchant (name, address, latitude, longitude, phone, description, mb_category_id, area_id, image, delivery, time_start, time_end, facebook, status, version, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)`
+
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logging.LoggerFromContext(ctx).Error(rbErr)
+			}
+		}
+	}()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, query, m.Name, m.Address, m.Latitude, m.Longitude, m.Phone, m.Description,
+		m.MbCategoryID, m.AreaID, m.Image, m.Delivery, m.TimeStart, m.TimeEnd, m.Facebook, m.Status, now, now)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if len(owner) != 0 {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO mb_merchant_owner (mb_merchant_id, subject, created_at) VALUES (?, ?, ?)`, lastID, owner, now); err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+			return err
+		}
+	}
+	if err = storeSnapshot(ctx, tx, lastID); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	m.ID = lastID
+	m.Version = 1
+	m.CreatedAt = null.TimeFrom(now)
+	m.UpdatedAt = null.TimeFrom(now)
+
+	return nil
+}
+
+func (a *mysqlMerchantRepository) Delete(ctx context.Context, id int64) error {
+	query := `UPDATE mb_merchant SET deleted_at = ?, version = version + 1, updated_at = ? WHERE mb_merchant_id = ? AND deleted_at is null`
+
+	now := time.Now()
+	res, err := a.DB.ExecContext(ctx, query, now, now, id)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	return checkAffected(res)
 }
@@ -0,0 +1,408 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"merchant-service/merchant"
+	"merchant-service/metrics"
+	"merchant-service/models"
+)
+
+// instrumentedMerchantRepository decorates a merchant.Repository with the duration and the errors of
+// every call, per method
+type instrumentedMerchantRepository struct {
+	merchant.Repository
+	metrics *metrics.Repository
+}
+
+// NewInstrumentedMerchantRepository will create an object that represent the merchant.Repository
+// interface, recording the calls made to next in m
+func NewInstrumentedMerchantRepository(next merchant.Repository, m *metrics.Repository) merchant.Repository {
+	return &instrumentedMerchantRepository{Repository: next, metrics: m}
+}
+
+func (r *instrumentedMerchantRepository) Fetch(ctx context.Context, page string, offset string) ([]*models.Merchant, int64, error) {
+	start := time.Now()
+	res, count, err := r.Repository.Fetch(ctx, page, offset)
+	r.metrics.Observe("merchant", "Fetch", start, err)
+	return res, count, err
+}
+
+func (r *instrumentedMerchantRepository) FetchChanges(ctx context.Context, since time.Time, afterID int64, limit int) ([]*models.Merchant, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchChanges(ctx, since, afterID, limit)
+	r.metrics.Observe("merchant", "FetchChanges", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) FetchByStatus(ctx context.Context, status string, page string, offset string) ([]*models.Merchant, int64, error) {
+	start := time.Now()
+	res, count, err := r.Repository.FetchByStatus(ctx, status, page, offset)
+	r.metrics.Observe("merchant", "FetchByStatus", start, err)
+	return res, count, err
+}
+
+func (r *instrumentedMerchantRepository) UpdateStatus(ctx context.Context, t *models.MerchantStatusTransition) error {
+	start := time.Now()
+	err := r.Repository.UpdateStatus(ctx, t)
+	r.metrics.Observe("merchant", "UpdateStatus", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchStatusTransitions(ctx context.Context, merchantID int64) ([]*models.MerchantStatusTransition, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchStatusTransitions(ctx, merchantID)
+	r.metrics.Observe("merchant", "FetchStatusTransitions", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) FetchOwners(ctx context.Context, id int64) ([]*models.MerchantOwner, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchOwners(ctx, id)
+	r.metrics.Observe("merchant", "FetchOwners", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) IsOwner(ctx context.Context, id int64, subject string) (bool, error) {
+	start := time.Now()
+	res, err := r.Repository.IsOwner(ctx, id, subject)
+	r.metrics.Observe("merchant", "IsOwner", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) StoreOwner(ctx context.Context, o *models.MerchantOwner) error {
+	start := time.Now()
+	err := r.Repository.StoreOwner(ctx, o)
+	r.metrics.Observe("merchant", "StoreOwner", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) DeleteOwner(ctx context.Context, id int64, subject string) error {
+	start := time.Now()
+	err := r.Repository.DeleteOwner(ctx, id, subject)
+	r.metrics.Observe("merchant", "DeleteOwner", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchClaims(ctx context.Context, status string) ([]*models.MerchantClaim, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchClaims(ctx, status)
+	r.metrics.Observe("merchant", "FetchClaims", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) FetchMerchantClaims(ctx context.Context, id int64) ([]*models.MerchantClaim, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchMerchantClaims(ctx, id)
+	r.metrics.Observe("merchant", "FetchMerchantClaims", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) CountSubjectClaims(ctx context.Context, subject string, since time.Time) (int64, error) {
+	start := time.Now()
+	res, err := r.Repository.CountSubjectClaims(ctx, subject, since)
+	r.metrics.Observe("merchant", "CountSubjectClaims", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) GetClaim(ctx context.Context, id int64) (*models.MerchantClaim, error) {
+	start := time.Now()
+	res, err := r.Repository.GetClaim(ctx, id)
+	r.metrics.Observe("merchant", "GetClaim", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) StoreClaim(ctx context.Context, c *models.MerchantClaim) error {
+	start := time.Now()
+	err := r.Repository.StoreClaim(ctx, c)
+	r.metrics.Observe("merchant", "StoreClaim", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) UpdateClaim(ctx context.Context, c *models.MerchantClaim, from string) error {
+	start := time.Now()
+	err := r.Repository.UpdateClaim(ctx, c, from)
+	r.metrics.Observe("merchant", "UpdateClaim", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) ApplyClaim(ctx context.Context, c *models.MerchantClaim, from string, exclusive bool) error {
+	start := time.Now()
+	err := r.Repository.ApplyClaim(ctx, c, from, exclusive)
+	r.metrics.Observe("merchant", "ApplyClaim", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchCategories(ctx context.Context) ([]*models.MbDiscoveryCategory, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchCategories(ctx)
+	r.metrics.Observe("merchant", "FetchCategories", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) GetCategoryByID(ctx context.Context, id int64) (*models.MbDiscoveryCategory, error) {
+	start := time.Now()
+	res, err := r.Repository.GetCategoryByID(ctx, id)
+	r.metrics.Observe("merchant", "GetCategoryByID", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) StoreCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	start := time.Now()
+	err := r.Repository.StoreCategory(ctx, m)
+	r.metrics.Observe("merchant", "StoreCategory", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) UpdateCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	start := time.Now()
+	err := r.Repository.UpdateCategory(ctx, m)
+	r.metrics.Observe("merchant", "UpdateCategory", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) DeleteCategory(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.Repository.DeleteCategory(ctx, id)
+	r.metrics.Observe("merchant", "DeleteCategory", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchArea(ctx context.Context) ([]*models.Area, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchArea(ctx)
+	r.metrics.Observe("merchant", "FetchArea", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) GetAreaByID(ctx context.Context, id int64) (*models.Area, error) {
+	start := time.Now()
+	res, err := r.Repository.GetAreaByID(ctx, id)
+	r.metrics.Observe("merchant", "GetAreaByID", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) StoreArea(ctx context.Context, m *models.Area) error {
+	start := time.Now()
+	err := r.Repository.StoreArea(ctx, m)
+	r.metrics.Observe("merchant", "StoreArea", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) UpdateArea(ctx context.Context, m *models.Area) error {
+	start := time.Now()
+	err := r.Repository.UpdateArea(ctx, m)
+	r.metrics.Observe("merchant", "UpdateArea", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) DeleteArea(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.Repository.DeleteArea(ctx, id)
+	r.metrics.Observe("merchant", "DeleteArea", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchActive(ctx context.Context) ([]*models.Merchant, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchActive(ctx)
+	r.metrics.Observe("merchant", "FetchActive", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) GetByID(ctx context.Context, id int64) (*models.Merchant, error) {
+	start := time.Now()
+	res, err := r.Repository.GetByID(ctx, id)
+	r.metrics.Observe("merchant", "GetByID", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) ResolveSlug(ctx context.Context, slug string) (int64, error) {
+	start := time.Now()
+	res, err := r.Repository.ResolveSlug(ctx, slug)
+	r.metrics.Observe("merchant", "ResolveSlug", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) SetSlug(ctx context.Context, id int64, slug string) error {
+	start := time.Now()
+	err := r.Repository.SetSlug(ctx, id, slug)
+	r.metrics.Observe("merchant", "SetSlug", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) GetMergedInto(ctx context.Context, id int64) (int64, error) {
+	start := time.Now()
+	res, err := r.Repository.GetMergedInto(ctx, id)
+	r.metrics.Observe("merchant", "GetMergedInto", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) Merge(ctx context.Context, survivorID int64, mergedID int64) error {
+	start := time.Now()
+	err := r.Repository.Merge(ctx, survivorID, mergedID)
+	r.metrics.Observe("merchant", "Merge", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) ReplaceDuplicateCandidates(ctx context.Context, list []*models.DuplicateCandidate) error {
+	start := time.Now()
+	err := r.Repository.ReplaceDuplicateCandidates(ctx, list)
+	r.metrics.Observe("merchant", "ReplaceDuplicateCandidates", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchDuplicateCandidates(ctx context.Context, status string, minScore float64) ([]*models.DuplicateCandidate, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchDuplicateCandidates(ctx, status, minScore)
+	r.metrics.Observe("merchant", "FetchDuplicateCandidates", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) UpdateDuplicateStatus(ctx context.Context, id int64, status string) error {
+	start := time.Now()
+	err := r.Repository.UpdateDuplicateStatus(ctx, id, status)
+	r.metrics.Observe("merchant", "UpdateDuplicateStatus", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) GetImagesByID(ctx context.Context, id int64) ([]*models.Image, error) {
+	start := time.Now()
+	res, err := r.Repository.GetImagesByID(ctx, id)
+	r.metrics.Observe("merchant", "GetImagesByID", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) StoreImage(ctx context.Context, img *models.Image) error {
+	start := time.Now()
+	err := r.Repository.StoreImage(ctx, img)
+	r.metrics.Observe("merchant", "StoreImage", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) ReplaceImages(ctx context.Context, id int64, images []*models.Image) error {
+	start := time.Now()
+	err := r.Repository.ReplaceImages(ctx, id, images)
+	r.metrics.Observe("merchant", "ReplaceImages", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) StoreRevision(ctx context.Context, rev *models.MerchantRevision) error {
+	start := time.Now()
+	err := r.Repository.StoreRevision(ctx, rev)
+	r.metrics.Observe("merchant", "StoreRevision", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) Revert(ctx context.Context, m *models.Merchant, rev *models.MerchantRevision) error {
+	start := time.Now()
+	err := r.Repository.Revert(ctx, m, rev)
+	r.metrics.Observe("merchant", "Revert", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) GetRevision(ctx context.Context, id int64, revision int64) (*models.MerchantRevision, error) {
+	start := time.Now()
+	res, err := r.Repository.GetRevision(ctx, id, revision)
+	r.metrics.Observe("merchant", "GetRevision", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) FetchRevisions(ctx context.Context, id int64) ([]*models.MerchantRevision, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchRevisions(ctx, id)
+	r.metrics.Observe("merchant", "FetchRevisions", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) FilterByMulti(ctx context.Context, filter *models.MerchantFilter, page string, offset string) ([]*models.Merchant, int64, error) {
+	start := time.Now()
+	res, count, err := r.Repository.FilterByMulti(ctx, filter, page, offset)
+	r.metrics.Observe("merchant", "FilterByMulti", start, err)
+	return res, count, err
+}
+
+func (r *instrumentedMerchantRepository) StreamLocated(ctx context.Context, filter *models.MerchantFilter, bbox *models.BoundingBox, fn func(*models.Merchant) error) error {
+	start := time.Now()
+	err := r.Repository.StreamLocated(ctx, filter, bbox, fn)
+	r.metrics.Observe("merchant", "StreamLocated", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) StreamApproved(ctx context.Context, page int, limit int, fn func(*models.Merchant) error) error {
+	start := time.Now()
+	err := r.Repository.StreamApproved(ctx, page, limit, fn)
+	r.metrics.Observe("merchant", "StreamApproved", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) FetchDeliveryZones(ctx context.Context, id int64) ([]*models.DeliveryZone, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchDeliveryZones(ctx, id)
+	r.metrics.Observe("merchant", "FetchDeliveryZones", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) FetchAllDeliveryZones(ctx context.Context) ([]*models.DeliveryZone, error) {
+	start := time.Now()
+	res, err := r.Repository.FetchAllDeliveryZones(ctx)
+	r.metrics.Observe("merchant", "FetchAllDeliveryZones", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) ReplaceDeliveryZones(ctx context.Context, id int64, zones []*models.DeliveryZone) error {
+	start := time.Now()
+	err := r.Repository.ReplaceDeliveryZones(ctx, id, zones)
+	r.metrics.Observe("merchant", "ReplaceDeliveryZones", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) GetDeliveryPricing(ctx context.Context, id int64) (*models.DeliveryPricing, error) {
+	start := time.Now()
+	res, err := r.Repository.GetDeliveryPricing(ctx, id)
+	r.metrics.Observe("merchant", "GetDeliveryPricing", start, err)
+	return res, err
+}
+
+func (r *instrumentedMerchantRepository) StoreDeliveryPricing(ctx context.Context, p *models.DeliveryPricing) error {
+	start := time.Now()
+	err := r.Repository.StoreDeliveryPricing(ctx, p)
+	r.metrics.Observe("merchant", "StoreDeliveryPricing", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) SearchByKeyword(ctx context.Context, title string) ([]*models.Merchant, int64, error) {
+	start := time.Now()
+	res, count, err := r.Repository.SearchByKeyword(ctx, title)
+	r.metrics.Observe("merchant", "SearchByKeyword", start, err)
+	return res, count, err
+}
+
+func (r *instrumentedMerchantRepository) Update(ctx context.Context, ar *models.Merchant) error {
+	start := time.Now()
+	err := r.Repository.Update(ctx, ar)
+	r.metrics.Observe("merchant", "Update", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) Store(ctx context.Context, a *models.Merchant, owner string) error {
+	start := time.Now()
+	err := r.Repository.Store(ctx, a, owner)
+	r.metrics.Observe("merchant", "Store", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) Delete(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.Repository.Delete(ctx, id)
+	r.metrics.Observe("merchant", "Delete", start, err)
+	return err
+}
+
+func (r *instrumentedMerchantRepository) GetCountRows(ctx context.Context, clause string) (int64, error) {
+	start := time.Now()
+	res, err := r.Repository.GetCountRows(ctx, clause)
+	r.metrics.Observe("merchant", "GetCountRows", start, err)
+	return res, err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("merchant-service/merchant/repository")
+
+// startStatement opens the span of one SQL statement, named after its verb so the data and the count
+// queries of a request tell apart at a glance, with the full statement attached
+func startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := "SQL"
+	if fields := strings.Fields(query); len(fields) != 0 {
+		name = strings.ToUpper(fields[0])
+	}
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBStatementKey.String(query)))
+}
+
+func endStatement(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// execer is what tracedDB and tracedTx have in common, so a statement helper runs on either
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// tracedDB runs every statement of the repository in a span of its own. A query's span covers its
+// execution, not the reading of the rows it returns.
+type tracedDB struct {
+	*sql.DB
+}
+
+func (db *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	endStatement(span, err)
+	return rows, err
+}
+
+func (db *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startStatement(ctx, query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	endStatement(span, row.Err())
+	return row
+}
+
+func (db *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startStatement(ctx, query)
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	endStatement(span, err)
+	return res, err
+}
+
+// BeginTx starts a transaction whose statements are traced too
+func (db *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{tx: tx}, nil
+}
+
+// tracedTx is the transaction counterpart of tracedDB. It wraps *sql.Tx rather than embedding it, so
+// no statement can slip past the tracing through a method left unwrapped.
+type tracedTx struct {
+	tx *sql.Tx
+}
+
+func (tx *tracedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := tx.tx.QueryContext(ctx, query, args...)
+	endStatement(span, err)
+	return rows, err
+}
+
+func (tx *tracedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startStatement(ctx, query)
+	res, err := tx.tx.ExecContext(ctx, query, args...)
+	endStatement(span, err)
+	return res, err
+}
+
+func (tx *tracedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startStatement(ctx, query)
+	row := tx.tx.QueryRowContext(ctx, query, args...)
+	endStatement(span, row.Err())
+	return row
+}
+
+func (tx *tracedTx) Commit() error {
+	return tx.tx.Commit()
+}
+
+func (tx *tracedTx) Rollback() error {
+	return tx.tx.Rollback()
+}
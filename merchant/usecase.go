@@ -2,6 +2,7 @@ package merchant
 
 import (
 	"context"
+	"time"
 
 	models "merchant-service/models"
 )
@@ -9,11 +10,58 @@ import (
 // Usecase represent the merchant's repository contract
 type Usecase interface {
 	Fetch(ctx context.Context, page string, offset string) ([]*models.Merchant, int64, error)
+	FetchChanges(ctx context.Context, since time.Time, cursor string, limit int) (*models.MerchantChanges, error)
+	FetchByStatus(ctx context.Context, status string, page string, offset string) ([]*models.Merchant, int64, error)
+	FetchApproved(ctx context.Context, page string, offset string) ([]*models.Merchant, int64, error)
+	Submit(ctx context.Context, id int64) error
+	Approve(ctx context.Context, id int64) error
+	Reject(ctx context.Context, id int64, reason string) error
+	Suspend(ctx context.Context, id int64, reason string) error
+	FetchStatusTransitions(ctx context.Context, id int64) ([]*models.MerchantStatusTransition, error)
+	FetchOwners(ctx context.Context, id int64) ([]*models.MerchantOwner, error)
+	AddOwner(ctx context.Context, id int64, subject string) error
+	RemoveOwner(ctx context.Context, id int64, subject string) error
+	RequestClaim(ctx context.Context, id int64) (*models.MerchantClaim, error)
+	VerifyClaim(ctx context.Context, claimID int64, code string) (*models.MerchantClaim, error)
+	DisputeClaim(ctx context.Context, claimID int64, reason string) error
+	ApproveClaim(ctx context.Context, claimID int64) error
+	RejectClaim(ctx context.Context, claimID int64, reason string) error
+	FetchClaims(ctx context.Context, status string) ([]*models.MerchantClaim, error)
 	FetchCategories(ctx context.Context) ([]*models.MbDiscoveryCategory, error)
+	StoreCategory(ctx context.Context, m *models.MbDiscoveryCategory) error
+	UpdateCategory(ctx context.Context, m *models.MbDiscoveryCategory) error
+	DeleteCategory(ctx context.Context, id int64) error
 	FetchArea(ctx context.Context) (res []*models.Area, err error)
+	StoreArea(ctx context.Context, m *models.Area) error
+	UpdateArea(ctx context.Context, m *models.Area) error
+	DeleteArea(ctx context.Context, id int64) error
 	GetByID(ctx context.Context, id int64) (*models.Merchant, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Merchant, error)
+	BackfillSlugs(ctx context.Context) (int, error)
+	GetMergedInto(ctx context.Context, id int64) (int64, error)
+	DetectDuplicates(ctx context.Context) (int, error)
+	FetchDuplicateCandidates(ctx context.Context, minScore float64) ([]*models.DuplicateCandidate, error)
+	DismissDuplicate(ctx context.Context, id int64) error
+	Merge(ctx context.Context, survivorID int64, mergedID int64) (*models.Merchant, error)
 	GetImagesByID(ctx context.Context, id int64) ([]*models.Image, error)
-	FilterByMulti(ctx context.Context, clause string, page string, offset string) ([]*models.Merchant, int64, error)
+	StoreImage(ctx context.Context, img *models.Image) error
+	FetchRevisions(ctx context.Context, id int64) ([]*models.MerchantRevision, error)
+	DiffRevisions(ctx context.Context, id int64, from int64, to int64) ([]*models.FieldChange, error)
+	Revert(ctx context.Context, id int64, revision int64) (*models.Merchant, error)
+	FilterByMulti(ctx context.Context, filter *models.MerchantFilter, page string, offset string) ([]*models.Merchant, int64, error)
+	Clusters(ctx context.Context, bbox *models.BoundingBox, zoom int, categoryIDs []int64, areaIDs []int64) ([]*models.MerchantCluster, error)
+	StreamLocated(ctx context.Context, filter *models.MerchantFilter, bbox *models.BoundingBox, fn func(*models.Merchant) error) error
+	StreamApproved(ctx context.Context, page int, limit int, fn func(*models.Merchant) error) error
+	FetchDeliveryZones(ctx context.Context, id int64) ([]*models.DeliveryZone, error)
+	ReplaceDeliveryZones(ctx context.Context, id int64, zones []*models.DeliveryZone) error
+	DeliversTo(ctx context.Context, lat float64, lng float64, categoryIDs []int64) ([]*models.Merchant, error)
+	GetDeliveryPricing(ctx context.Context, id int64) (*models.DeliveryPricing, error)
+	StoreDeliveryPricing(ctx context.Context, p *models.DeliveryPricing) error
+	DeliveryQuote(ctx context.Context, id int64, lat float64, lng float64, subtotal float64) (*models.DeliveryQuote, error)
+	BackfillCoordinates(ctx context.Context) (int, error)
+	FetchOutsideArea(ctx context.Context) ([]*models.AreaMismatch, error)
+	LocateArea(ctx context.Context, lat float64, lng float64) (*models.Area, error)
+	SuggestArea(ctx context.Context, m *models.Merchant) (*models.Area, error)
 	SearchByKeyword(ctx context.Context, title string) ([]*models.Merchant, int64, error)
 	Update(ctx context.Context, ar *models.Merchant) error
 	Store(ctx context.Context, a *models.Merchant) error
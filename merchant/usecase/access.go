@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+
+	"merchant-service/models"
+)
+
+// authorize lets the caller through when it holds role or one above it
+func authorize(ctx context.Context, role string) error {
+	p := models.PrincipalFromContext(ctx)
+	if p == nil {
+		return models.ErrUnauthorized
+	}
+	if !p.HasRole(role) {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// authorizeMerchant lets editors through on every merchant, and owners on the merchants they own
+func (a *merchantUsecase) authorizeMerchant(c context.Context, id int64) error {
+	if err := authorize(c, models.RoleOwner); err != nil {
+		return err
+	}
+	p := models.PrincipalFromContext(c)
+	if p.HasRole(models.RoleEditor) {
+		return nil
+	}
+	if p.Kind != models.PrincipalUser {
+		return models.ErrForbidden
+	}
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	owned, err := a.merchantRepo.IsOwner(ctx, id, p.Subject)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// ownerSubjects lists the subjects of owners, the form they take in the audit trail
+func ownerSubjects(owners []*models.MerchantOwner) []string {
+	subjects := make([]string, 0, len(owners))
+	for _, o := range owners {
+		subjects = append(subjects, o.Subject)
+	}
+	return subjects
+}
+
+func (a *merchantUsecase) FetchOwners(c context.Context, id int64) ([]*models.MerchantOwner, error) {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if _, err := a.merchantRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return a.merchantRepo.FetchOwners(ctx, id)
+}
+
+// changeOwners applies change to the owners of a merchant and audits the owners before and after
+func (a *merchantUsecase) changeOwners(c context.Context, id int64, change func(ctx context.Context) error) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if _, err := a.merchantRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	before, err := a.merchantRepo.FetchOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := change(ctx); err != nil {
+		return err
+	}
+	after, err := a.merchantRepo.FetchOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	a.record(ctx, models.ActionUpdate, models.EntityMerchant, id,
+		map[string]interface{}{"owners": ownerSubjects(before)},
+		map[string]interface{}{"owners": ownerSubjects(after)})
+
+	return nil
+}
+
+func (a *merchantUsecase) AddOwner(c context.Context, id int64, subject string) error {
+	if len(subject) == 0 {
+		return models.ErrBadParamInput
+	}
+	return a.changeOwners(c, id, func(ctx context.Context) error {
+		return a.merchantRepo.StoreOwner(ctx, &models.MerchantOwner{MerchantID: id, Subject: subject})
+	})
+}
+
+func (a *merchantUsecase) RemoveOwner(c context.Context, id int64, subject string) error {
+	return a.changeOwners(c, id, func(ctx context.Context) error {
+		return a.merchantRepo.DeleteOwner(ctx, id, subject)
+	})
+}
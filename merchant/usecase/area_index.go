@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"merchant-service/logging"
+	"merchant-service/merchant"
+	"merchant-service/models"
+)
+
+type areaEntry struct {
+	area   *models.Area
+	bounds models.BoundingBox
+}
+
+// size is the extent of the entry's bounds, used to prefer the narrower of two overlapping areas
+func (e *areaEntry) size() float64 {
+	return (e.bounds.MaxLng - e.bounds.MinLng) * (e.bounds.MaxLat - e.bounds.MinLat)
+}
+
+// areaIndex keeps the areas having a boundary in memory. There are few areas, so a lookup scans
+// them all and the bounds only spare the polygon tests.
+type areaIndex struct {
+	mu       sync.RWMutex
+	repo     merchant.Repository
+	entries  []*areaEntry
+	loadedAt time.Time
+}
+
+func newAreaIndex(repo merchant.Repository) *areaIndex {
+	return &areaIndex{repo: repo}
+}
+
+// invalidate forces the next lookup to reload the index
+func (x *areaIndex) invalidate() {
+	x.mu.Lock()
+	x.loadedAt = time.Time{}
+	x.mu.Unlock()
+}
+
+func (x *areaIndex) load(ctx context.Context) error {
+	x.mu.RLock()
+	fresh := time.Since(x.loadedAt) < geoIndexTTL
+	x.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	areas, err := x.repo.FetchArea(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make([]*areaEntry, 0, len(areas))
+	for _, ar := range areas {
+		if len(ar.Boundary) != 0 {
+			entries = append(entries, &areaEntry{area: ar, bounds: ar.Boundary.Bounds()})
+		}
+	}
+
+	x.mu.Lock()
+	x.entries = entries
+	x.loadedAt = time.Now()
+	x.mu.Unlock()
+	return nil
+}
+
+// locate returns the area whose boundary holds the point, the narrowest one when areas overlap
+func (x *areaIndex) locate(lat float64, lng float64) *models.Area {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var best *areaEntry
+	for _, e := range x.entries {
+		if !e.bounds.Contains(lat, lng) || !e.area.Boundary.Contains(lat, lng) {
+			continue
+		}
+		if best == nil || e.size() < best.size() {
+			best = e
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.area
+}
+
+func (a *merchantUsecase) LocateArea(c context.Context, lat float64, lng float64) (*models.Area, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.areas.load(ctx); err != nil {
+		return nil, err
+	}
+	ar := a.areas.locate(lat, lng)
+	if ar == nil {
+		return nil, models.ErrNotFound
+	}
+	return ar, nil
+}
+
+// contains reports whether the area id has a boundary and the point is inside it; an area without a
+// boundary cannot be checked and is taken to contain every point
+func (x *areaIndex) contains(id int64, lat float64, lng float64) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	for _, e := range x.entries {
+		if e.area.ID == id {
+			return e.bounds.Contains(lat, lng) && e.area.Boundary.Contains(lat, lng)
+		}
+	}
+	return true
+}
+
+// assignArea fills in the area of a located merchant sent without one, and moves a merchant whose
+// coordinates left the boundary of its area to the area now holding them. A point no area holds
+// keeps the area it has, for FetchOutsideArea to report. It only helps the caller, so a failure to
+// load the areas is logged and the merchant saved as sent.
+func (a *merchantUsecase) assignArea(ctx context.Context, m *models.Merchant) {
+	if !m.Latitude.Valid || !m.Longitude.Valid {
+		return
+	}
+	if err := a.areas.load(ctx); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return
+	}
+	lat, lng := m.Latitude.Float64, m.Longitude.Float64
+	if m.AreaID.Valid && a.areas.contains(m.AreaID.Int64, lat, lng) {
+		return
+	}
+	if ar := a.areas.locate(lat, lng); ar != nil {
+		m.AreaID.SetValid(ar.ID)
+	}
+}
+
+// SuggestArea returns the area holding the merchant's coordinates when it is not the one the
+// merchant is assigned to
+func (a *merchantUsecase) SuggestArea(c context.Context, m *models.Merchant) (*models.Area, error) {
+	if !m.Latitude.Valid || !m.Longitude.Valid {
+		return nil, models.ErrNotFound
+	}
+	ar, err := a.LocateArea(c, m.Latitude.Float64, m.Longitude.Float64)
+	if err != nil {
+		return nil, err
+	}
+	if m.AreaID.Valid && m.AreaID.Int64 == ar.ID {
+		return nil, models.ErrNotFound
+	}
+	return ar, nil
+}
+
+// FetchOutsideArea reports the located merchants whose coordinates fall outside the boundary of
+// their area, with the area suggested by their coordinates. Merchants of areas without a boundary
+// cannot be checked and are left out.
+func (a *merchantUsecase) FetchOutsideArea(c context.Context) ([]*models.AreaMismatch, error) {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.areas.load(ctx); err != nil {
+		return nil, err
+	}
+	list, err := a.merchantRepo.FetchActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a.areas.mu.RLock()
+	boundaries := make(map[int64]models.Polygon, len(a.areas.entries))
+	for _, e := range a.areas.entries {
+		boundaries[e.area.ID] = e.area.Boundary
+	}
+	a.areas.mu.RUnlock()
+
+	results := make([]*models.AreaMismatch, 0)
+	for _, m := range list {
+		if !m.Latitude.Valid || !m.Longitude.Valid || !m.AreaID.Valid {
+			continue
+		}
+		boundary, ok := boundaries[m.AreaID.Int64]
+		if !ok || boundary.Contains(m.Latitude.Float64, m.Longitude.Float64) {
+			continue
+		}
+		results = append(results, &models.AreaMismatch{
+			Merchant:      m,
+			SuggestedArea: a.areas.locate(m.Latitude.Float64, m.Longitude.Float64),
+		})
+	}
+
+	return results, nil
+}
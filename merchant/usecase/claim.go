@@ -0,0 +1,302 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+const (
+	// claimCodeTTL is how long a verification code sent to the merchant's phone stays valid
+	claimCodeTTL = 15 * time.Minute
+	// maxClaimAttempts is the number of wrong codes after which a claim is rejected
+	maxClaimAttempts = 5
+	// claimWindow is the period the claim quotas below are counted over
+	claimWindow = 24 * time.Hour
+	// maxMerchantClaims caps the claims opened on one merchant per window, whoever opens them, so its
+	// phone cannot be flooded with codes
+	maxMerchantClaims = 3
+	// maxSubjectClaims caps the claims one user opens per window, on any merchant
+	maxSubjectClaims = 5
+	// claimCooldown is how long a user waits before claiming a merchant again after a claim of theirs
+	// on it was rejected or expired
+	claimCooldown = time.Hour
+)
+
+// claimTransitions lists, for each claim status, the statuses it may move to
+var claimTransitions = map[string][]string{
+	models.ClaimPending:  {models.ClaimApproved, models.ClaimDisputed, models.ClaimRejected},
+	models.ClaimApproved: {models.ClaimDisputed},
+	models.ClaimDisputed: {models.ClaimApproved, models.ClaimRejected},
+}
+
+func canTransitionClaim(from string, to string) bool {
+	for _, s := range claimTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// newClaimCode returns a random six digit code
+func newClaimCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashClaimCode binds the code to its claim so a stored hash cannot be replayed on another one
+func hashClaimCode(c *models.MerchantClaim, code string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s", c.MerchantID, c.Subject, code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// lastClaimActivity is when a claim was rejected, or for one left pending, when its code expired
+func lastClaimActivity(cl *models.MerchantClaim) time.Time {
+	if cl.Status == models.ClaimPending {
+		return cl.ExpiresAt
+	}
+	return cl.UpdatedAt
+}
+
+// claimant returns the user principal of ctx; services cannot own a merchant
+func claimant(ctx context.Context) (*models.Principal, error) {
+	if err := authorize(ctx, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	p := models.PrincipalFromContext(ctx)
+	if p.Kind != models.PrincipalUser {
+		return nil, models.ErrForbidden
+	}
+	return p, nil
+}
+
+// setClaimStatus moves a claim to another status, adding or removing the claimant's ownership with it
+// in the same transaction. An exclusive approval fails with ErrAlreadyOwned, leaving the claim as it
+// was, when the merchant already has an owner.
+func (a *merchantUsecase) setClaimStatus(ctx context.Context, claim *models.MerchantClaim, to string, reason string, exclusive bool) error {
+	if !canTransitionClaim(claim.Status, to) {
+		return models.ErrInvalidTransition
+	}
+	before := *claim
+	claim.Status = to
+	claim.Reason = null.NewString(reason, len(reason) != 0)
+	if err := a.merchantRepo.ApplyClaim(ctx, claim, before.Status, exclusive); err != nil {
+		*claim = before
+		return err
+	}
+	a.record(ctx, models.ActionStatus, models.EntityClaim, claim.ID,
+		map[string]interface{}{"status": before.Status},
+		map[string]interface{}{"status": claim.Status, "reason": claim.Reason})
+
+	return nil
+}
+
+// RequestClaim opens a claim on a merchant for the calling user and sends a verification code to
+// the merchant's phone
+func (a *merchantUsecase) RequestClaim(c context.Context, id int64) (*models.MerchantClaim, error) {
+	p, err := claimant(c)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	m, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(m.Phone.String)) == 0 {
+		// there is nowhere to send a code, an admin has to assign the owner
+		return nil, models.ErrBadParamInput
+	}
+	owned, err := a.merchantRepo.IsOwner(ctx, id, p.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if owned {
+		return nil, models.ErrConflict
+	}
+	claims, err := a.merchantRepo.FetchMerchantClaims(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	recent := 0
+	for _, cl := range claims {
+		if cl.Subject == p.Subject && (cl.Status == models.ClaimDisputed || (cl.Status == models.ClaimPending && now.Before(cl.ExpiresAt))) {
+			return nil, models.ErrConflict
+		}
+		if now.Sub(cl.CreatedAt) < claimWindow {
+			recent++
+		}
+		if cl.Subject == p.Subject && cl.Status != models.ClaimApproved && now.Sub(lastClaimActivity(cl)) < claimCooldown {
+			return nil, models.ErrTooManyRequests
+		}
+	}
+	if recent >= maxMerchantClaims {
+		return nil, models.ErrTooManyRequests
+	}
+	opened, err := a.merchantRepo.CountSubjectClaims(ctx, p.Subject, now.Add(-claimWindow))
+	if err != nil {
+		return nil, err
+	}
+	if opened >= maxSubjectClaims {
+		return nil, models.ErrTooManyRequests
+	}
+
+	code, err := newClaimCode()
+	if err != nil {
+		return nil, err
+	}
+	claim := &models.MerchantClaim{
+		MerchantID: id,
+		Subject:    p.Subject,
+		Status:     models.ClaimPending,
+		ExpiresAt:  now.Add(claimCodeTTL),
+	}
+	claim.CodeHash = hashClaimCode(claim, code)
+	if err := a.merchantRepo.StoreClaim(ctx, claim); err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ActionCreate, models.EntityClaim, claim.ID, nil, claim)
+
+	message := fmt.Sprintf("Your code to claim %s is %s. It expires in %d minutes.", m.Name.String, code, int(claimCodeTTL.Minutes()))
+	if err := a.notifier.Send(ctx, m.Phone.String, message); err != nil {
+		if rejectErr := a.setClaimStatus(ctx, claim, models.ClaimRejected, "verification code could not be delivered", false); rejectErr != nil {
+			logging.LoggerFromContext(ctx).Error(rejectErr)
+		}
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+// VerifyClaim checks the code of a pending claim. A correct code makes the claimant an owner of an
+// unowned merchant; a merchant that already has owners leaves the claim disputed for an admin.
+func (a *merchantUsecase) VerifyClaim(c context.Context, claimID int64, code string) (*models.MerchantClaim, error) {
+	p, err := claimant(c)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	claim, err := a.merchantRepo.GetClaim(ctx, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if claim.Subject != p.Subject {
+		return nil, models.ErrForbidden
+	}
+	if claim.Status != models.ClaimPending {
+		return nil, models.ErrInvalidTransition
+	}
+	if time.Now().After(claim.ExpiresAt) {
+		return nil, models.ErrBadParamInput
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashClaimCode(claim, code)), []byte(claim.CodeHash)) != 1 {
+		claim.Attempts++
+		if claim.Attempts >= maxClaimAttempts {
+			err = a.setClaimStatus(ctx, claim, models.ClaimRejected, "too many wrong verification codes", false)
+		} else {
+			err = a.merchantRepo.UpdateClaim(ctx, claim, models.ClaimPending)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, models.ErrBadParamInput
+	}
+
+	err = a.setClaimStatus(ctx, claim, models.ClaimApproved, "", true)
+	if err == models.ErrAlreadyOwned {
+		err = a.setClaimStatus(ctx, claim, models.ClaimDisputed, "merchant already has an owner", false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+// DisputeClaim lets an owner of the merchant contest a claim, taking back an ownership it granted
+// until an admin decides
+func (a *merchantUsecase) DisputeClaim(c context.Context, claimID int64, reason string) error {
+	if len(reason) == 0 {
+		return models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	claim, err := a.merchantRepo.GetClaim(ctx, claimID)
+	if err != nil {
+		return err
+	}
+	if err := a.authorizeMerchant(ctx, claim.MerchantID); err != nil {
+		return err
+	}
+	return a.setClaimStatus(ctx, claim, models.ClaimDisputed, reason, false)
+}
+
+func (a *merchantUsecase) ApproveClaim(c context.Context, claimID int64) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	claim, err := a.merchantRepo.GetClaim(ctx, claimID)
+	if err != nil {
+		return err
+	}
+	// pending claims are approved by their verification code, not by an admin
+	if claim.Status != models.ClaimDisputed {
+		return models.ErrInvalidTransition
+	}
+	return a.setClaimStatus(ctx, claim, models.ClaimApproved, "", false)
+}
+
+func (a *merchantUsecase) RejectClaim(c context.Context, claimID int64, reason string) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	if len(reason) == 0 {
+		return models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	claim, err := a.merchantRepo.GetClaim(ctx, claimID)
+	if err != nil {
+		return err
+	}
+	return a.setClaimStatus(ctx, claim, models.ClaimRejected, reason, false)
+}
+
+func (a *merchantUsecase) FetchClaims(c context.Context, status string) ([]*models.MerchantClaim, error) {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if _, ok := claimTransitions[status]; !ok && status != models.ClaimRejected {
+		return nil, models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.FetchClaims(ctx, status)
+}
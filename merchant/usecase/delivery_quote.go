@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"math"
+
+	"merchant-service/models"
+)
+
+const (
+	// roadFactor stretches the straight-line distance into a rough road distance
+	roadFactor = 1.3
+	// courierSpeedKmh is the average speed of a courier in city traffic
+	courierSpeedKmh = 20.0
+	// feeRounding rounds fees up to a payable amount, in VND
+	feeRounding = 1000.0
+)
+
+// quoteFee prices a delivery of the given road distance, nothing when the subtotal passes the free threshold
+func quoteFee(p *models.DeliveryPricing, roadMeters float64, subtotal float64) float64 {
+	if p.FreeOverSubtotal.Valid && subtotal >= p.FreeOverSubtotal.Float64 {
+		return 0
+	}
+	fee := p.BaseFee + p.PerKmFee*roadMeters/1000
+	return math.Ceil(fee/feeRounding) * feeRounding
+}
+
+// quoteEta adds the preparation time to the courier travel time, in minutes
+func quoteEta(p *models.DeliveryPricing, roadMeters float64) int {
+	travel := roadMeters / 1000 / courierSpeedKmh * 60
+	return p.PrepMinutes + int(math.Ceil(travel))
+}
+
+func (a *merchantUsecase) GetDeliveryPricing(c context.Context, id int64) (*models.DeliveryPricing, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	m, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != models.StatusApproved {
+		return nil, models.ErrNotFound
+	}
+	return a.merchantRepo.GetDeliveryPricing(ctx, id)
+}
+
+func (a *merchantUsecase) StoreDeliveryPricing(c context.Context, p *models.DeliveryPricing) error {
+	if err := a.authorizeMerchant(c, p.MerchantID); err != nil {
+		return err
+	}
+	if p.BaseFee < 0 || p.PerKmFee < 0 || p.PrepMinutes < 0 ||
+		(p.FreeOverSubtotal.Valid && p.FreeOverSubtotal.Float64 < 0) || (p.MinOrder.Valid && p.MinOrder.Float64 < 0) {
+		return models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if _, err := a.merchantRepo.GetByID(ctx, p.MerchantID); err != nil {
+		return err
+	}
+	before, err := a.merchantRepo.GetDeliveryPricing(ctx, p.MerchantID)
+	if err != nil && err != models.ErrNotFound {
+		return err
+	}
+	if err := a.merchantRepo.StoreDeliveryPricing(ctx, p); err != nil {
+		return err
+	}
+	a.record(ctx, models.ActionUpdate, models.EntityMerchant, p.MerchantID,
+		map[string]interface{}{"delivery_pricing": before},
+		map[string]interface{}{"delivery_pricing": p})
+
+	return nil
+}
+
+// DeliveryQuote estimates the fee and time of delivering an order of subtotal to the point. An
+// ineligible quote is not an error, it carries the reason instead.
+func (a *merchantUsecase) DeliveryQuote(c context.Context, id int64, lat float64, lng float64, subtotal float64) (*models.DeliveryQuote, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	m, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != models.StatusApproved {
+		return nil, models.ErrNotFound
+	}
+
+	quote := &models.DeliveryQuote{MerchantID: id}
+	if m.Delivery.Int64 != 1 {
+		quote.Reason = models.QuoteNoDelivery
+		return quote, nil
+	}
+	if !m.Latitude.Valid || !m.Longitude.Valid {
+		quote.Reason = models.QuoteNoLocation
+		return quote, nil
+	}
+	pricing, err := a.merchantRepo.GetDeliveryPricing(ctx, id)
+	if err == models.ErrNotFound {
+		quote.Reason = models.QuoteNoPricing
+		return quote, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	roadMeters := models.Distance(m.Latitude.Float64, m.Longitude.Float64, lat, lng) * roadFactor
+	quote.DistanceMeters = math.Round(roadMeters)
+	quote.Fee = quoteFee(pricing, roadMeters, subtotal)
+	quote.EtaMinutes = quoteEta(pricing, roadMeters)
+
+	// a merchant without zones has not limited where it delivers
+	zones, err := a.merchantRepo.FetchDeliveryZones(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(zones) != 0 {
+		covered := false
+		for _, zone := range zones {
+			if (&zoneEntry{zone: zone, merchant: m}).contains(lat, lng) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			quote.Reason = models.QuoteOutsideZones
+			return quote, nil
+		}
+	}
+	if pricing.MinOrder.Valid && subtotal < pricing.MinOrder.Float64 {
+		quote.Reason = models.QuoteBelowMinimum
+		return quote, nil
+	}
+
+	quote.Eligible = true
+	return quote, nil
+}
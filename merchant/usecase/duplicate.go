@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"merchant-service/models"
+)
+
+const (
+	// minDuplicateScore is the lowest score a pair needs to be kept as a candidate
+	minDuplicateScore = 0.6
+	// maxDuplicateDistance is the distance in meters past which location adds nothing to the score
+	maxDuplicateDistance = 500.0
+	// gridCellDegrees sizes the location buckets, roughly one kilometer
+	gridCellDegrees = 0.01
+	// maxBlockSize skips a bucket with more merchants than this, such as a chain sharing one name or a
+	// dense city block, since it would add its size squared in pairs while telling few of them apart
+	maxBlockSize = 200
+	// duplicateTimeout bounds a whole detection run, which reads and scores the full catalog and takes
+	// far longer than a request
+	duplicateTimeout = 10 * time.Minute
+
+	nameWeight     = 0.5
+	phoneWeight    = 0.3
+	distanceWeight = 0.2
+)
+
+type duplicatePair struct {
+	a int
+	b int
+}
+
+// candidatePairs blocks merchants by phone, normalized name and location cell so only pairs sharing
+// one of them are scored, instead of every pair in the catalog
+func candidatePairs(list []*models.Merchant, names []string, phones []string) map[duplicatePair]bool {
+	pairs := map[duplicatePair]bool{}
+	addBucket := func(bucket []int) {
+		if len(bucket) > maxBlockSize {
+			return
+		}
+		for x := 0; x < len(bucket); x++ {
+			for y := x + 1; y < len(bucket); y++ {
+				a, b := bucket[x], bucket[y]
+				if a > b {
+					a, b = b, a
+				}
+				pairs[duplicatePair{a, b}] = true
+			}
+		}
+	}
+
+	buckets := map[string][]int{}
+	cells := map[[2]int][]int{}
+	for i, m := range list {
+		if len(phones[i]) >= 8 {
+			buckets["phone:"+phones[i]] = append(buckets["phone:"+phones[i]], i)
+		}
+		if len(names[i]) != 0 {
+			buckets["name:"+names[i]] = append(buckets["name:"+names[i]], i)
+		}
+		if m.Latitude.Valid && m.Longitude.Valid {
+			cell := [2]int{int(math.Floor(m.Latitude.Float64 / gridCellDegrees)), int(math.Floor(m.Longitude.Float64 / gridCellDegrees))}
+			cells[cell] = append(cells[cell], i)
+		}
+	}
+	for _, bucket := range buckets {
+		addBucket(bucket)
+	}
+	for cell, members := range cells {
+		// a merchant near a cell edge can sit next to one in the neighboring cell
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx == 0 && dy == 0 {
+					addBucket(members)
+					continue
+				}
+				neighbors := cells[[2]int{cell[0] + dx, cell[1] + dy}]
+				if len(members) > maxBlockSize || len(neighbors) > maxBlockSize {
+					continue
+				}
+				for _, a := range members {
+					for _, b := range neighbors {
+						if a < b {
+							pairs[duplicatePair{a, b}] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// findDuplicates scores merchant pairs by name similarity, phone match and distance
+func findDuplicates(list []*models.Merchant) []*models.DuplicateCandidate {
+	names := make([]string, len(list))
+	phones := make([]string, len(list))
+	for i, m := range list {
+		names[i] = normalizeName(m.Name.String)
+		phones[i] = normalizePhone(m.Phone.String)
+	}
+
+	results := make([]*models.DuplicateCandidate, 0)
+	for p := range candidatePairs(list, names, phones) {
+		ma, mb := list[p.a], list[p.b]
+		d := &models.DuplicateCandidate{
+			MerchantID:  ma.ID,
+			DuplicateID: mb.ID,
+			NameScore:   similarity(names[p.a], names[p.b]),
+			PhoneMatch:  len(phones[p.a]) != 0 && phones[p.a] == phones[p.b],
+		}
+		distanceScore := 0.0
+		if ma.Latitude.Valid && ma.Longitude.Valid && mb.Latitude.Valid && mb.Longitude.Valid {
+			meters := models.Distance(ma.Latitude.Float64, ma.Longitude.Float64, mb.Latitude.Float64, mb.Longitude.Float64)
+			d.DistanceMeters = null.FloatFrom(meters)
+			distanceScore = math.Max(0, 1-meters/maxDuplicateDistance)
+		}
+		d.Score = nameWeight*d.NameScore + distanceWeight*distanceScore
+		if d.PhoneMatch {
+			d.Score += phoneWeight
+		}
+		if d.Score >= minDuplicateScore {
+			results = append(results, d)
+		}
+	}
+	return results
+}
+
+func (a *merchantUsecase) DetectDuplicates(c context.Context) (int, error) {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(c, duplicateTimeout)
+	defer cancel()
+
+	list, err := a.merchantRepo.FetchActive(ctx)
+	if err != nil {
+		return 0, err
+	}
+	candidates := findDuplicates(list)
+	if err := a.merchantRepo.ReplaceDuplicateCandidates(ctx, candidates); err != nil {
+		return 0, err
+	}
+
+	return len(candidates), nil
+}
+
+func (a *merchantUsecase) FetchDuplicateCandidates(c context.Context, minScore float64) ([]*models.DuplicateCandidate, error) {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.FetchDuplicateCandidates(ctx, models.DuplicatePending, minScore)
+}
+
+func (a *merchantUsecase) DismissDuplicate(c context.Context, id int64) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.UpdateDuplicateStatus(ctx, id, models.DuplicateDismissed)
+}
+
+func (a *merchantUsecase) GetMergedInto(c context.Context, id int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.GetMergedInto(ctx, id)
+}
+
+// Merge folds mergedID into survivorID: the images move over, the merged merchant is deleted and
+// its ID redirects to the survivor from then on
+func (a *merchantUsecase) Merge(c context.Context, survivorID int64, mergedID int64) (*models.Merchant, error) {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if survivorID == mergedID {
+		return nil, models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	survivor, err := a.merchantRepo.GetByID(ctx, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := a.merchantRepo.GetByID(ctx, mergedID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.merchantRepo.Merge(ctx, survivorID, mergedID); err != nil {
+		return nil, err
+	}
+	a.merchantsMoved()
+
+	after, err := a.merchantRepo.GetByID(ctx, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ActionMerge, models.EntityMerchant, mergedID, merged,
+		map[string]interface{}{"merged_into": survivorID})
+	a.record(ctx, models.ActionMerge, models.EntityMerchant, survivorID, survivor, after)
+
+	return after, nil
+}
@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"merchant-service/merchant"
+	"merchant-service/models"
+)
+
+const (
+	// geoIndexCell is the size in degrees of the index buckets, roughly one kilometer
+	geoIndexCell = 0.01
+	// geoIndexTTL bounds how stale the index gets when writes bypass this instance
+	geoIndexTTL = time.Minute
+	// maxClusterZoom is the zoom level from which merchants are returned one by one
+	maxClusterZoom = 16
+	// clusterCellsPerTile splits each map tile into this many cluster cells per side
+	clusterCellsPerTile = 4
+	// maxClusterPoints caps the merchants returned one by one, for a wide box at a high zoom
+	maxClusterPoints = 500
+)
+
+type cellKey [2]int
+
+// geoIndex keeps the approved, located merchants in memory, bucketed by a fixed grid
+type geoIndex struct {
+	mu sync.RWMutex
+	// build lets a single caller rebuild a stale index while the others wait for it
+	build    sync.Mutex
+	repo     merchant.Repository
+	cells    map[cellKey][]*models.Merchant
+	loadedAt time.Time
+}
+
+func newGeoIndex(repo merchant.Repository) *geoIndex {
+	return &geoIndex{repo: repo}
+}
+
+func cellOf(lat float64, lng float64, size float64) cellKey {
+	return cellKey{int(math.Floor(lng / size)), int(math.Floor(lat / size))}
+}
+
+// invalidate forces the next query to reload the index
+func (g *geoIndex) invalidate() {
+	g.mu.Lock()
+	g.loadedAt = time.Time{}
+	g.mu.Unlock()
+}
+
+func (g *geoIndex) fresh() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return time.Since(g.loadedAt) < geoIndexTTL
+}
+
+func (g *geoIndex) load(ctx context.Context) error {
+	if g.fresh() {
+		return nil
+	}
+	g.build.Lock()
+	defer g.build.Unlock()
+	// another caller may have rebuilt it while this one waited
+	if g.fresh() {
+		return nil
+	}
+
+	cells := map[cellKey][]*models.Merchant{}
+	err := g.repo.StreamLocated(ctx, nil, nil, func(m *models.Merchant) error {
+		key := cellOf(m.Latitude.Float64, m.Longitude.Float64, geoIndexCell)
+		cells[key] = append(cells[key], m)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.cells = cells
+	g.loadedAt = time.Now()
+	g.mu.Unlock()
+	return nil
+}
+
+// search calls fn for every indexed merchant inside bbox until fn returns false
+func (g *geoIndex) search(bbox *models.BoundingBox, fn func(m *models.Merchant) bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	visit := func(list []*models.Merchant) bool {
+		for _, m := range list {
+			if bbox.Contains(m.Latitude.Float64, m.Longitude.Float64) && !fn(m) {
+				return false
+			}
+		}
+		return true
+	}
+	lo := cellOf(bbox.MinLat, bbox.MinLng, geoIndexCell)
+	hi := cellOf(bbox.MaxLat, bbox.MaxLng, geoIndexCell)
+	// a wide box covers more grid cells than exist, so walking the occupied ones is cheaper
+	if (hi[0]-lo[0]+1)*(hi[1]-lo[1]+1) > len(g.cells) {
+		for key, list := range g.cells {
+			if key[0] >= lo[0] && key[0] <= hi[0] && key[1] >= lo[1] && key[1] <= hi[1] && !visit(list) {
+				return
+			}
+		}
+		return
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			if !visit(g.cells[cellKey{x, y}]) {
+				return
+			}
+		}
+	}
+}
+
+func matchesAny(v null.Int, ids []int64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if v.Valid && v.Int64 == id {
+			return true
+		}
+	}
+	return false
+}
+
+type clusterAcc struct {
+	count      int
+	latSum     float64
+	lngSum     float64
+	categories map[int64]int
+	first      *models.Merchant
+}
+
+func (a *merchantUsecase) Clusters(c context.Context, bbox *models.BoundingBox, zoom int, categoryIDs []int64, areaIDs []int64) ([]*models.MerchantCluster, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.geo.load(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.MerchantCluster, 0)
+	if zoom >= maxClusterZoom {
+		a.geo.search(bbox, func(m *models.Merchant) bool {
+			if matchesAny(m.MbCategoryID, categoryIDs) && matchesAny(m.AreaID, areaIDs) {
+				results = append(results, &models.MerchantCluster{
+					Count:         1,
+					Latitude:      m.Latitude.Float64,
+					Longitude:     m.Longitude.Float64,
+					TopCategoryID: m.MbCategoryID,
+					Merchant:      m,
+				})
+			}
+			return len(results) < maxClusterPoints
+		})
+		return results, nil
+	}
+
+	size := 360 / math.Pow(2, float64(zoom)) / clusterCellsPerTile
+	groups := map[cellKey]*clusterAcc{}
+	order := make([]cellKey, 0)
+	a.geo.search(bbox, func(m *models.Merchant) bool {
+		if !matchesAny(m.MbCategoryID, categoryIDs) || !matchesAny(m.AreaID, areaIDs) {
+			return true
+		}
+		key := cellOf(m.Latitude.Float64, m.Longitude.Float64, size)
+		acc, ok := groups[key]
+		if !ok {
+			acc = &clusterAcc{categories: map[int64]int{}, first: m}
+			groups[key] = acc
+			order = append(order, key)
+		}
+		acc.count++
+		acc.latSum += m.Latitude.Float64
+		acc.lngSum += m.Longitude.Float64
+		if m.MbCategoryID.Valid {
+			acc.categories[m.MbCategoryID.Int64]++
+		}
+		return true
+	})
+
+	for _, key := range order {
+		acc := groups[key]
+		cluster := &models.MerchantCluster{
+			Count:     acc.count,
+			Latitude:  acc.latSum / float64(acc.count),
+			Longitude: acc.lngSum / float64(acc.count),
+		}
+		best := 0
+		for id, n := range acc.categories {
+			if n > best || (n == best && id < cluster.TopCategoryID.Int64) {
+				best = n
+				cluster.TopCategoryID = null.IntFrom(id)
+			}
+		}
+		if acc.count == 1 {
+			cluster.Merchant = acc.first
+		}
+		results = append(results, cluster)
+	}
+	return results, nil
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"gopkg.in/guregu/null.v3"
+
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// BackfillCoordinates geocodes the address of every merchant without coordinates. Each merchant
+// gets its own timeout, since a remote geocoder may be throttled; addresses the geocoder does not
+// know and merchants edited meanwhile are skipped and left for a later run.
+func (a *merchantUsecase) BackfillCoordinates(c context.Context) (int, error) {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	list, err := a.merchantRepo.FetchActive(ctx)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range list {
+		if (m.Latitude.Valid && m.Longitude.Valid) || len(strings.TrimSpace(m.Address.String)) == 0 {
+			continue
+		}
+		ok, err := a.geocodeMerchant(c, m)
+		if err != nil {
+			return count, err
+		}
+		if ok {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (a *merchantUsecase) geocodeMerchant(c context.Context, m *models.Merchant) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	res, err := a.geocoder.Geocode(ctx, m.Address.String)
+	if err == models.ErrNotFound {
+		logging.LoggerFromContext(ctx).Warnf("no coordinates found for merchant %d address %q", m.ID, m.Address.String)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	before := *m
+	m.Latitude = null.FloatFrom(res.Latitude)
+	m.Longitude = null.FloatFrom(res.Longitude)
+	a.assignArea(ctx, m)
+	if err := a.merchantRepo.Update(ctx, m); err != nil {
+		if err == models.ErrConflict || err == models.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	a.merchantsMoved()
+	a.record(ctx, models.ActionUpdate, models.EntityMerchant, m.ID, &before, m)
+
+	return true, nil
+}
@@ -2,25 +2,89 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+
+	"merchant-service/audit"
+	"merchant-service/geocoder"
+	"merchant-service/logging"
 	"merchant-service/merchant"
 	"merchant-service/models"
+	"merchant-service/notifier"
 )
 
+// maxChangesLimit caps the number of rows returned by one page of the sync feed
+const maxChangesLimit = 500
+
+// statusTransitions lists, for each moderation status, the statuses it may move to
+var statusTransitions = map[string][]string{
+	models.StatusDraft:     {models.StatusPending},
+	models.StatusPending:   {models.StatusApproved, models.StatusRejected},
+	models.StatusRejected:  {models.StatusPending},
+	models.StatusApproved:  {models.StatusSuspended},
+	models.StatusSuspended: {models.StatusApproved},
+}
+
+func canTransition(from string, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
 type merchantUsecase struct {
 	merchantRepo   merchant.Repository
+	auditUsecase   audit.Usecase
+	geocoder       geocoder.Geocoder
+	notifier       notifier.Notifier
+	geo            *geoIndex
+	zones          *zoneIndex
+	areas          *areaIndex
 	contextTimeout time.Duration
 }
 
 // NewMerchantUsecase will create new an merchantUsecase object representation of merchant.Usecase interface
-func NewMerchantUsecase(a merchant.Repository, timeout time.Duration) merchant.Usecase {
+func NewMerchantUsecase(a merchant.Repository, au audit.Usecase, g geocoder.Geocoder, n notifier.Notifier, timeout time.Duration) merchant.Usecase {
 	return &merchantUsecase{
 		merchantRepo:   a,
+		auditUsecase:   au,
+		geocoder:       g,
+		notifier:       n,
+		geo:            newGeoIndex(a),
+		zones:          newZoneIndex(a),
+		areas:          newAreaIndex(a),
 		contextTimeout: timeout,
 	}
 }
 
+// record writes an audit entry for a write that already committed. Failing the request then would
+// report a write that happened as lost and invite a retry repeating it, so a failure is logged with
+// the entry it dropped, for the trail to be restored from the logs.
+func (a *merchantUsecase) record(ctx context.Context, action string, entityType string, id int64, before interface{}, after interface{}) {
+	if err := a.auditUsecase.Record(ctx, action, entityType, id, before, after); err != nil {
+		logging.LoggerFromContext(ctx).WithFields(logrus.Fields{
+			"action":      action,
+			"entity_type": entityType,
+			"entity_id":   id,
+			"before":      before,
+			"after":       after,
+		}).Error("audit entry lost: ", err)
+	}
+}
+
+// merchantsMoved marks the indexes built from merchant locations, status and delivery zones stale;
+// call it after every write that may change one of them
+func (a *merchantUsecase) merchantsMoved() {
+	a.geo.invalidate()
+	a.zones.invalidate()
+}
+
 func (a *merchantUsecase) GetCountRows(c context.Context, clause string) (int64, error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
@@ -45,6 +109,65 @@ func (a *merchantUsecase) Fetch(c context.Context, page string, offset string) (
 	return listAr, count, nil
 }
 
+func encodeChangesCursor(at time.Time, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", at.UnixNano(), id)))
+}
+
+func decodeChangesCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, models.ErrBadParamInput
+	}
+	var nanos, id int64
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil {
+		return time.Time{}, 0, models.ErrBadParamInput
+	}
+	return time.Unix(0, nanos), id, nil
+}
+
+func (a *merchantUsecase) FetchChanges(c context.Context, since time.Time, cursor string, limit int) (*models.MerchantChanges, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	var afterID int64
+	if len(cursor) != 0 {
+		var err error
+		since, afterID, err = decodeChangesCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 || limit > maxChangesLimit {
+		limit = maxChangesLimit
+	}
+
+	// fetch one extra row to know whether another page follows
+	list, err := a.merchantRepo.FetchChanges(ctx, since, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.MerchantChanges{
+		Upserted: make([]*models.Merchant, 0),
+		Deleted:  make([]int64, 0),
+	}
+	if len(list) > limit {
+		list = list[:limit]
+		res.HasMore = true
+	}
+	for _, m := range list {
+		if m.DeletedAt.Valid || m.Status != models.StatusApproved {
+			res.Deleted = append(res.Deleted, m.ID)
+		} else {
+			res.Upserted = append(res.Upserted, m)
+		}
+		since, afterID = m.UpdatedAt.Time, m.ID
+	}
+	res.NextCursor = encodeChangesCursor(since, afterID)
+
+	return res, nil
+}
+
 func (a *merchantUsecase) FetchArea(c context.Context) ([]*models.Area, error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
@@ -77,6 +200,9 @@ func (a *merchantUsecase) GetByID(c context.Context, id int64) (*models.Merchant
 	if err != nil {
 		return nil, err
 	}
+	if res.Status != models.StatusApproved {
+		return nil, models.ErrNotFound
+	}
 
 	return res, nil
 }
@@ -93,11 +219,11 @@ func (a *merchantUsecase) GetImagesByID(c context.Context, id int64) ([]*models.
 	return res, nil
 }
 
-func (a *merchantUsecase) FilterByMulti(c context.Context, clause string, page string, offset string) ([]*models.Merchant, int64, error) {
+func (a *merchantUsecase) FilterByMulti(c context.Context, filter *models.MerchantFilter, page string, offset string) ([]*models.Merchant, int64, error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
 
-	res, count, err := a.merchantRepo.FilterByMulti(ctx, clause, page, offset)
+	res, count, err := a.merchantRepo.FilterByMulti(ctx, filter, page, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -105,6 +231,18 @@ func (a *merchantUsecase) FilterByMulti(c context.Context, clause string, page s
 	return res, count, nil
 }
 
+// StreamLocated calls fn for every approved merchant with coordinates matching the filters, without
+// buffering the result set. The context timeout is not applied since a large export outlives it.
+func (a *merchantUsecase) StreamLocated(ctx context.Context, filter *models.MerchantFilter, bbox *models.BoundingBox, fn func(*models.Merchant) error) error {
+	return a.merchantRepo.StreamLocated(ctx, filter, bbox, fn)
+}
+
+// StreamApproved calls fn for each public merchant of a page, for anyone, without buffering the page.
+// Like StreamLocated it runs without the context timeout.
+func (a *merchantUsecase) StreamApproved(ctx context.Context, page int, limit int, fn func(*models.Merchant) error) error {
+	return a.merchantRepo.StreamApproved(ctx, page, limit, fn)
+}
+
 func (a *merchantUsecase) SearchByKeyword(c context.Context, keyword string) ([]*models.Merchant, int64, error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
@@ -117,14 +255,395 @@ func (a *merchantUsecase) SearchByKeyword(c context.Context, keyword string) ([]
 	return res, count, nil
 }
 
-func (a *merchantUsecase) Update(ctx context.Context, m *models.Merchant) error {
+func (a *merchantUsecase) Update(c context.Context, m *models.Merchant) error {
+	if err := a.authorizeMerchant(c, m.ID); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetByID(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	a.assignArea(ctx, m)
+	if err := a.merchantRepo.Update(ctx, m); err != nil {
+		if err == models.ErrConflict {
+			// hand the caller the representation that won so it can retry against it
+			if current, getErr := a.merchantRepo.GetByID(ctx, m.ID); getErr == nil {
+				*m = *current
+			}
+		}
+		return err
+	}
+	a.merchantsMoved()
+	m.Slug = before.Slug
+	if err := a.assignSlug(ctx, m); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+	}
+	after, err := a.merchantRepo.GetByID(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	a.record(ctx, models.ActionUpdate, models.EntityMerchant, m.ID, before, after)
+	*m = *after
+
+	return nil
+}
+
+func (a *merchantUsecase) Store(c context.Context, m *models.Merchant) error {
+	if err := authorize(c, models.RoleOwner); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	// new merchants always start in draft; the status only moves through transitions
+	m.Status = models.StatusDraft
+	m.Slug = null.String{}
+	a.assignArea(ctx, m)
+	// an owner creating a shop gets to edit it; editors can edit every merchant already
+	owner := ""
+	if p := models.PrincipalFromContext(ctx); !p.HasRole(models.RoleEditor) && p.Kind == models.PrincipalUser {
+		owner = p.Subject
+	}
+	if err := a.merchantRepo.Store(ctx, m, owner); err != nil {
+		return err
+	}
+	a.merchantsMoved()
+	if err := a.assignSlug(ctx, m); err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+	}
+	a.record(ctx, models.ActionCreate, models.EntityMerchant, m.ID, nil, m)
+
+	return nil
+}
+
+func (a *merchantUsecase) Delete(c context.Context, id int64) error {
+	if err := a.authorizeMerchant(c, id); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	a.merchantsMoved()
+	a.record(ctx, models.ActionDelete, models.EntityMerchant, id, before, nil)
+
+	return nil
+}
+
+func (a *merchantUsecase) StoreImage(c context.Context, img *models.Image) error {
+	if err := a.authorizeMerchant(c, img.MerchantID); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetByID(ctx, img.MerchantID)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.StoreImage(ctx, img); err != nil {
+		return err
+	}
+	after, err := a.merchantRepo.GetByID(ctx, img.MerchantID)
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return nil
+	}
+	a.record(ctx, models.ActionUpdate, models.EntityMerchant, img.MerchantID, before, after)
+
+	return nil
+}
+
+func (a *merchantUsecase) StoreCategory(c context.Context, m *models.MbDiscoveryCategory) error {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.merchantRepo.StoreCategory(ctx, m); err != nil {
+		return err
+	}
+	a.record(ctx, models.ActionCreate, models.EntityCategory, m.ID, nil, m)
+
+	return nil
+}
+
+func (a *merchantUsecase) UpdateCategory(c context.Context, m *models.MbDiscoveryCategory) error {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetCategoryByID(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.UpdateCategory(ctx, m); err != nil {
+		return err
+	}
+	a.record(ctx, models.ActionUpdate, models.EntityCategory, m.ID, before, m)
+
+	return nil
+}
+
+func (a *merchantUsecase) DeleteCategory(c context.Context, id int64) error {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.DeleteCategory(ctx, id); err != nil {
+		return err
+	}
+	a.record(ctx, models.ActionDelete, models.EntityCategory, id, before, nil)
+
+	return nil
+}
+
+func (a *merchantUsecase) StoreArea(c context.Context, m *models.Area) error {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return err
+	}
+	if len(m.Boundary) != 0 && !m.Boundary.Valid() {
+		return models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.merchantRepo.StoreArea(ctx, m); err != nil {
+		return err
+	}
+	a.areas.invalidate()
+	a.record(ctx, models.ActionCreate, models.EntityArea, m.ID, nil, m)
+
 	return nil
 }
 
-func (a *merchantUsecase) Store(ctx context.Context, m *models.Merchant) error {
+func (a *merchantUsecase) UpdateArea(c context.Context, m *models.Area) error {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return err
+	}
+	if len(m.Boundary) != 0 && !m.Boundary.Valid() {
+		return models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetAreaByID(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.UpdateArea(ctx, m); err != nil {
+		return err
+	}
+	a.areas.invalidate()
+	a.record(ctx, models.ActionUpdate, models.EntityArea, m.ID, before, m)
+
 	return nil
 }
 
-func (a *merchantUsecase) Delete(ctx context.Context, id int64) error {
+func (a *merchantUsecase) DeleteArea(c context.Context, id int64) error {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetAreaByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.DeleteArea(ctx, id); err != nil {
+		return err
+	}
+	a.areas.invalidate()
+	a.record(ctx, models.ActionDelete, models.EntityArea, id, before, nil)
+
 	return nil
 }
+
+func (a *merchantUsecase) FetchByStatus(c context.Context, status string, page string, offset string) ([]*models.Merchant, int64, error) {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return nil, 0, err
+	}
+	if _, ok := statusTransitions[status]; !ok {
+		return nil, 0, models.ErrBadParamInput
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.FetchByStatus(ctx, status, page, offset)
+}
+
+// FetchApproved lists the public merchants, in the order the admin listing uses, for anyone
+func (a *merchantUsecase) FetchApproved(c context.Context, page string, offset string) ([]*models.Merchant, int64, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.FetchByStatus(ctx, models.StatusApproved, page, offset)
+}
+
+func (a *merchantUsecase) transition(c context.Context, id int64, to string, reason string) error {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	m, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !canTransition(m.Status, to) {
+		return models.ErrInvalidTransition
+	}
+
+	t := &models.MerchantStatusTransition{
+		MerchantID: id,
+		FromStatus: m.Status,
+		ToStatus:   to,
+		Reason:     null.NewString(reason, len(reason) != 0),
+		Actor:      models.ActorFromContext(ctx),
+	}
+	if err := a.merchantRepo.UpdateStatus(ctx, t); err != nil {
+		return err
+	}
+	a.merchantsMoved()
+	a.record(ctx, models.ActionStatus, models.EntityMerchant, id,
+		map[string]interface{}{"status": t.FromStatus},
+		map[string]interface{}{"status": t.ToStatus, "reason": t.Reason})
+
+	return nil
+}
+
+func (a *merchantUsecase) Submit(c context.Context, id int64) error {
+	if err := a.authorizeMerchant(c, id); err != nil {
+		return err
+	}
+	return a.transition(c, id, models.StatusPending, "")
+}
+
+func (a *merchantUsecase) Approve(c context.Context, id int64) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	return a.transition(c, id, models.StatusApproved, "")
+}
+
+func (a *merchantUsecase) Reject(c context.Context, id int64, reason string) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	if len(reason) == 0 {
+		return models.ErrBadParamInput
+	}
+	return a.transition(c, id, models.StatusRejected, reason)
+}
+
+func (a *merchantUsecase) Suspend(c context.Context, id int64, reason string) error {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return err
+	}
+	if len(reason) == 0 {
+		return models.ErrBadParamInput
+	}
+	return a.transition(c, id, models.StatusSuspended, reason)
+}
+
+func (a *merchantUsecase) FetchStatusTransitions(c context.Context, id int64) ([]*models.MerchantStatusTransition, error) {
+	if err := authorize(c, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if _, err := a.merchantRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return a.merchantRepo.FetchStatusTransitions(ctx, id)
+}
+
+func (a *merchantUsecase) FetchRevisions(c context.Context, id int64) ([]*models.MerchantRevision, error) {
+	// revisions keep snapshots of pending and rejected states the public reads hide
+	if err := a.authorizeMerchant(c, id); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.merchantRepo.FetchRevisions(ctx, id)
+}
+
+func (a *merchantUsecase) DiffRevisions(c context.Context, id int64, from int64, to int64) ([]*models.FieldChange, error) {
+	if err := a.authorizeMerchant(c, id); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	before, err := a.merchantRepo.GetRevision(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := a.merchantRepo.GetRevision(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.auditUsecase.Diff(before.Snapshot, after.Snapshot)
+}
+
+// Revert restores the fields and images of an earlier revision; the moderation status is left alone
+// because it only moves through transitions. The restored state is stored as a new revision.
+func (a *merchantUsecase) Revert(c context.Context, id int64, revision int64) (*models.Merchant, error) {
+	if err := a.authorizeMerchant(c, id); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	target, err := a.merchantRepo.GetRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	before, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the snapshot's fields and gallery come back; what the revert does not write is kept as it is now
+	restored := *target.Snapshot
+	restored.ID = id
+	restored.Version = before.Version
+	restored.Slug = before.Slug
+	restored.Status = before.Status
+	restored.CreatedAt = before.CreatedAt
+	restored.DeletedAt = before.DeletedAt
+	// the boundaries may have moved since the snapshot was taken
+	a.assignArea(ctx, &restored)
+	if err := a.merchantRepo.Revert(ctx, &restored, &models.MerchantRevision{Actor: models.ActorFromContext(ctx)}); err != nil {
+		return nil, err
+	}
+	a.merchantsMoved()
+
+	after, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a.record(ctx, models.ActionRevert, models.EntityMerchant, id, before, after)
+
+	return after, nil
+}
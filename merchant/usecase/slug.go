@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"merchant-service/models"
+)
+
+const (
+	// maxSlugLength keeps generated slugs readable in URLs
+	maxSlugLength = 80
+	// maxSlugAttempts bounds the numeric suffixes tried when a slug is taken
+	maxSlugAttempts = 100
+)
+
+// slugify turns a merchant name into a URL slug, e.g. "Phở Thìn Lò Đúc" to "pho-thin-lo-duc"
+func slugify(name string) string {
+	slug := strings.Replace(normalizeName(name), " ", "-", -1)
+	if r := []rune(slug); len(r) > maxSlugLength {
+		slug = strings.TrimRight(string(r[:maxSlugLength]), "-")
+	}
+	if len(slug) == 0 {
+		return "merchant"
+	}
+	return slug
+}
+
+// slugMatches reports whether slug was generated from base, with or without a numeric suffix
+func slugMatches(slug string, base string) bool {
+	if slug == base {
+		return true
+	}
+	if !strings.HasPrefix(slug, base+"-") {
+		return false
+	}
+	_, err := strconv.Atoi(strings.TrimPrefix(slug, base+"-"))
+	return err == nil
+}
+
+// assignSlug gives m a unique slug derived from its name, unless its current slug already is one
+func (a *merchantUsecase) assignSlug(ctx context.Context, m *models.Merchant) error {
+	base := slugify(m.Name.String)
+	if m.Slug.Valid && slugMatches(m.Slug.String, base) {
+		return nil
+	}
+
+	for i := 1; i <= maxSlugAttempts; i++ {
+		slug := base
+		if i > 1 {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		err := a.merchantRepo.SetSlug(ctx, m.ID, slug)
+		if err == models.ErrConflict {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.Slug.SetValid(slug)
+		return nil
+	}
+	return models.ErrConflict
+}
+
+func (a *merchantUsecase) GetBySlug(c context.Context, slug string) (*models.Merchant, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	id, err := a.merchantRepo.ResolveSlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != models.StatusApproved {
+		return nil, models.ErrNotFound
+	}
+
+	return res, nil
+}
+
+// BackfillSlugs assigns slugs to merchants stored before slugs existed, each under its own timeout,
+// and returns how many were assigned, also when it stops on an error
+func (a *merchantUsecase) BackfillSlugs(c context.Context) (int, error) {
+	if err := authorize(c, models.RoleAdmin); err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	list, err := a.merchantRepo.FetchActive(ctx)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range list {
+		if m.Slug.Valid {
+			continue
+		}
+		if err := a.backfillSlug(c, m); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func (a *merchantUsecase) backfillSlug(c context.Context, m *models.Merchant) error {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.assignSlug(ctx, m)
+}
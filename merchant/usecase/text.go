@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// dStroke covers the Vietnamese letters that carry no combining mark to strip
+var dStroke = strings.NewReplacer("đ", "d", "Đ", "D")
+
+// removeDiacritics folds Vietnamese text to plain ASCII letters, e.g. "Phở Thìn" to "Pho Thin"
+func removeDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, dStroke.Replace(s))
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+// normalizeName lowercases, strips diacritics and collapses everything but letters and digits to single spaces
+func normalizeName(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(removeDiacritics(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// normalizePhone keeps the digits of a phone number and rewrites the +84 prefix to the local 0
+func normalizePhone(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if strings.HasPrefix(digits, "84") && len(digits) > 9 {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
+
+// similarity returns the normalized Levenshtein similarity of a and b, from 0 to 1
+func similarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,441 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"merchant-service/merchant"
+	"merchant-service/models"
+)
+
+var tracer = otel.Tracer("merchant-service/merchant/usecase")
+
+// tracedMerchantUsecase decorates a merchant.Usecase with a span around every call, so the
+// repository statements of a request hang under the use case that ran them
+type tracedMerchantUsecase struct {
+	merchant.Usecase
+}
+
+// NewTracedMerchantUsecase will create an object that represent the merchant.Usecase interface,
+// tracing the calls made to next
+func NewTracedMerchantUsecase(next merchant.Usecase) merchant.Usecase {
+	return &tracedMerchantUsecase{Usecase: next}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (u *tracedMerchantUsecase) Fetch(ctx context.Context, page string, offset string) ([]*models.Merchant, int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Fetch")
+	res, count, err := u.Usecase.Fetch(ctx, page, offset)
+	endSpan(span, err)
+	return res, count, err
+}
+
+func (u *tracedMerchantUsecase) FetchChanges(ctx context.Context, since time.Time, cursor string, limit int) (*models.MerchantChanges, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchChanges")
+	res, err := u.Usecase.FetchChanges(ctx, since, cursor, limit)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) FetchByStatus(ctx context.Context, status string, page string, offset string) ([]*models.Merchant, int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchByStatus")
+	res, count, err := u.Usecase.FetchByStatus(ctx, status, page, offset)
+	endSpan(span, err)
+	return res, count, err
+}
+
+func (u *tracedMerchantUsecase) FetchApproved(ctx context.Context, page string, offset string) ([]*models.Merchant, int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchApproved")
+	res, count, err := u.Usecase.FetchApproved(ctx, page, offset)
+	endSpan(span, err)
+	return res, count, err
+}
+
+func (u *tracedMerchantUsecase) Submit(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Submit")
+	err := u.Usecase.Submit(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) Approve(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Approve")
+	err := u.Usecase.Approve(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) Reject(ctx context.Context, id int64, reason string) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Reject")
+	err := u.Usecase.Reject(ctx, id, reason)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) Suspend(ctx context.Context, id int64, reason string) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Suspend")
+	err := u.Usecase.Suspend(ctx, id, reason)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) FetchStatusTransitions(ctx context.Context, id int64) ([]*models.MerchantStatusTransition, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchStatusTransitions")
+	res, err := u.Usecase.FetchStatusTransitions(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) FetchOwners(ctx context.Context, id int64) ([]*models.MerchantOwner, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchOwners")
+	res, err := u.Usecase.FetchOwners(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) AddOwner(ctx context.Context, id int64, subject string) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.AddOwner")
+	err := u.Usecase.AddOwner(ctx, id, subject)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) RemoveOwner(ctx context.Context, id int64, subject string) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.RemoveOwner")
+	err := u.Usecase.RemoveOwner(ctx, id, subject)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) RequestClaim(ctx context.Context, id int64) (*models.MerchantClaim, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.RequestClaim")
+	res, err := u.Usecase.RequestClaim(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) VerifyClaim(ctx context.Context, claimID int64, code string) (*models.MerchantClaim, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.VerifyClaim")
+	res, err := u.Usecase.VerifyClaim(ctx, claimID, code)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) DisputeClaim(ctx context.Context, claimID int64, reason string) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DisputeClaim")
+	err := u.Usecase.DisputeClaim(ctx, claimID, reason)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) ApproveClaim(ctx context.Context, claimID int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.ApproveClaim")
+	err := u.Usecase.ApproveClaim(ctx, claimID)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) RejectClaim(ctx context.Context, claimID int64, reason string) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.RejectClaim")
+	err := u.Usecase.RejectClaim(ctx, claimID, reason)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) FetchClaims(ctx context.Context, status string) ([]*models.MerchantClaim, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchClaims")
+	res, err := u.Usecase.FetchClaims(ctx, status)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) FetchCategories(ctx context.Context) ([]*models.MbDiscoveryCategory, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchCategories")
+	res, err := u.Usecase.FetchCategories(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) StoreCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.StoreCategory")
+	err := u.Usecase.StoreCategory(ctx, m)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) UpdateCategory(ctx context.Context, m *models.MbDiscoveryCategory) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.UpdateCategory")
+	err := u.Usecase.UpdateCategory(ctx, m)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) DeleteCategory(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DeleteCategory")
+	err := u.Usecase.DeleteCategory(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) FetchArea(ctx context.Context) ([]*models.Area, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchArea")
+	res, err := u.Usecase.FetchArea(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) StoreArea(ctx context.Context, m *models.Area) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.StoreArea")
+	err := u.Usecase.StoreArea(ctx, m)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) UpdateArea(ctx context.Context, m *models.Area) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.UpdateArea")
+	err := u.Usecase.UpdateArea(ctx, m)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) DeleteArea(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DeleteArea")
+	err := u.Usecase.DeleteArea(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) GetByID(ctx context.Context, id int64) (*models.Merchant, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.GetByID")
+	res, err := u.Usecase.GetByID(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) GetBySlug(ctx context.Context, slug string) (*models.Merchant, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.GetBySlug")
+	res, err := u.Usecase.GetBySlug(ctx, slug)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) BackfillSlugs(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.BackfillSlugs")
+	res, err := u.Usecase.BackfillSlugs(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) GetMergedInto(ctx context.Context, id int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.GetMergedInto")
+	res, err := u.Usecase.GetMergedInto(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) DetectDuplicates(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DetectDuplicates")
+	res, err := u.Usecase.DetectDuplicates(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) FetchDuplicateCandidates(ctx context.Context, minScore float64) ([]*models.DuplicateCandidate, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchDuplicateCandidates")
+	res, err := u.Usecase.FetchDuplicateCandidates(ctx, minScore)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) DismissDuplicate(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DismissDuplicate")
+	err := u.Usecase.DismissDuplicate(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) Merge(ctx context.Context, survivorID int64, mergedID int64) (*models.Merchant, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Merge")
+	res, err := u.Usecase.Merge(ctx, survivorID, mergedID)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) GetImagesByID(ctx context.Context, id int64) ([]*models.Image, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.GetImagesByID")
+	res, err := u.Usecase.GetImagesByID(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) StoreImage(ctx context.Context, img *models.Image) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.StoreImage")
+	err := u.Usecase.StoreImage(ctx, img)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) FetchRevisions(ctx context.Context, id int64) ([]*models.MerchantRevision, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchRevisions")
+	res, err := u.Usecase.FetchRevisions(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) DiffRevisions(ctx context.Context, id int64, from int64, to int64) ([]*models.FieldChange, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DiffRevisions")
+	res, err := u.Usecase.DiffRevisions(ctx, id, from, to)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) Revert(ctx context.Context, id int64, revision int64) (*models.Merchant, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Revert")
+	res, err := u.Usecase.Revert(ctx, id, revision)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) FilterByMulti(ctx context.Context, filter *models.MerchantFilter, page string, offset string) ([]*models.Merchant, int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FilterByMulti")
+	res, count, err := u.Usecase.FilterByMulti(ctx, filter, page, offset)
+	endSpan(span, err)
+	return res, count, err
+}
+
+func (u *tracedMerchantUsecase) Clusters(ctx context.Context, bbox *models.BoundingBox, zoom int, categoryIDs []int64, areaIDs []int64) ([]*models.MerchantCluster, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Clusters")
+	res, err := u.Usecase.Clusters(ctx, bbox, zoom, categoryIDs, areaIDs)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) StreamLocated(ctx context.Context, filter *models.MerchantFilter, bbox *models.BoundingBox, fn func(*models.Merchant) error) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.StreamLocated")
+	err := u.Usecase.StreamLocated(ctx, filter, bbox, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) StreamApproved(ctx context.Context, page int, limit int, fn func(*models.Merchant) error) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.StreamApproved")
+	err := u.Usecase.StreamApproved(ctx, page, limit, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) FetchDeliveryZones(ctx context.Context, id int64) ([]*models.DeliveryZone, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchDeliveryZones")
+	res, err := u.Usecase.FetchDeliveryZones(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) ReplaceDeliveryZones(ctx context.Context, id int64, zones []*models.DeliveryZone) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.ReplaceDeliveryZones")
+	err := u.Usecase.ReplaceDeliveryZones(ctx, id, zones)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) DeliversTo(ctx context.Context, lat float64, lng float64, categoryIDs []int64) ([]*models.Merchant, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DeliversTo")
+	res, err := u.Usecase.DeliversTo(ctx, lat, lng, categoryIDs)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) GetDeliveryPricing(ctx context.Context, id int64) (*models.DeliveryPricing, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.GetDeliveryPricing")
+	res, err := u.Usecase.GetDeliveryPricing(ctx, id)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) StoreDeliveryPricing(ctx context.Context, p *models.DeliveryPricing) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.StoreDeliveryPricing")
+	err := u.Usecase.StoreDeliveryPricing(ctx, p)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) DeliveryQuote(ctx context.Context, id int64, lat float64, lng float64, subtotal float64) (*models.DeliveryQuote, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.DeliveryQuote")
+	res, err := u.Usecase.DeliveryQuote(ctx, id, lat, lng, subtotal)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) BackfillCoordinates(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.BackfillCoordinates")
+	res, err := u.Usecase.BackfillCoordinates(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) FetchOutsideArea(ctx context.Context) ([]*models.AreaMismatch, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.FetchOutsideArea")
+	res, err := u.Usecase.FetchOutsideArea(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) LocateArea(ctx context.Context, lat float64, lng float64) (*models.Area, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.LocateArea")
+	res, err := u.Usecase.LocateArea(ctx, lat, lng)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) SuggestArea(ctx context.Context, m *models.Merchant) (*models.Area, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.SuggestArea")
+	res, err := u.Usecase.SuggestArea(ctx, m)
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *tracedMerchantUsecase) SearchByKeyword(ctx context.Context, title string) ([]*models.Merchant, int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.SearchByKeyword")
+	res, count, err := u.Usecase.SearchByKeyword(ctx, title)
+	endSpan(span, err)
+	return res, count, err
+}
+
+func (u *tracedMerchantUsecase) Update(ctx context.Context, ar *models.Merchant) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Update")
+	err := u.Usecase.Update(ctx, ar)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) Store(ctx context.Context, a *models.Merchant) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Store")
+	err := u.Usecase.Store(ctx, a)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.Delete")
+	err := u.Usecase.Delete(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (u *tracedMerchantUsecase) GetCountRows(ctx context.Context, clause string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "merchantUsecase.GetCountRows")
+	res, err := u.Usecase.GetCountRows(ctx, clause)
+	endSpan(span, err)
+	return res, err
+}
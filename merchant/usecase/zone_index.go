@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"merchant-service/logging"
+	"merchant-service/merchant"
+	"merchant-service/models"
+)
+
+// zoneIndexCell is the size in degrees of the delivery zone index buckets, roughly five kilometers
+const zoneIndexCell = 0.05
+
+// metersPerDegree is the length of one degree of latitude
+const metersPerDegree = 111320.0
+
+const (
+	// maxZoneSpan is the widest a delivery zone may be in degrees on either axis, about 110 km, which
+	// keeps a zone to at most a few hundred index cells
+	maxZoneSpan = 1.0
+	// maxZoneRadius is the largest radius zone in meters, well within maxZoneSpan
+	maxZoneRadius = 50000.0
+	// maxZonesPerMerchant bounds how many zones one merchant adds to the index
+	maxZonesPerMerchant = 20
+)
+
+// fits reports whether the box is small enough to be indexed
+func fits(b models.BoundingBox) bool {
+	return b.MaxLat-b.MinLat <= maxZoneSpan && b.MaxLng-b.MinLng <= maxZoneSpan
+}
+
+type zoneEntry struct {
+	zone     *models.DeliveryZone
+	merchant *models.Merchant
+}
+
+// contains reports whether the zone covers the point, radius zones being centered on the merchant
+func (e *zoneEntry) contains(lat float64, lng float64) bool {
+	if len(e.zone.Polygon) != 0 {
+		return e.zone.Polygon.Contains(lat, lng)
+	}
+	return models.Distance(e.merchant.Latitude.Float64, e.merchant.Longitude.Float64, lat, lng) <= e.zone.RadiusMeters.Float64
+}
+
+func (e *zoneEntry) bounds() models.BoundingBox {
+	if len(e.zone.Polygon) != 0 {
+		return e.zone.Polygon.Bounds()
+	}
+	lat, lng := e.merchant.Latitude.Float64, e.merchant.Longitude.Float64
+	dLat := e.zone.RadiusMeters.Float64 / metersPerDegree
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	return models.BoundingBox{MinLng: lng - dLng, MinLat: lat - dLat, MaxLng: lng + dLng, MaxLat: lat + dLat}
+}
+
+// zoneIndex keeps the delivery zones of approved merchants in memory, each registered in every
+// grid cell its bounds overlap so a lookup only tests the zones of one cell
+type zoneIndex struct {
+	mu       sync.RWMutex
+	repo     merchant.Repository
+	cells    map[cellKey][]*zoneEntry
+	loadedAt time.Time
+}
+
+func newZoneIndex(repo merchant.Repository) *zoneIndex {
+	return &zoneIndex{repo: repo}
+}
+
+// invalidate forces the next lookup to reload the index
+func (z *zoneIndex) invalidate() {
+	z.mu.Lock()
+	z.loadedAt = time.Time{}
+	z.mu.Unlock()
+}
+
+func (z *zoneIndex) load(ctx context.Context) error {
+	z.mu.RLock()
+	fresh := time.Since(z.loadedAt) < geoIndexTTL
+	z.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	list, _, err := z.repo.FetchByStatus(ctx, models.StatusApproved, "", "")
+	if err != nil {
+		return err
+	}
+	merchants := make(map[int64]*models.Merchant, len(list))
+	for _, m := range list {
+		merchants[m.ID] = m
+	}
+	zones, err := z.repo.FetchAllDeliveryZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	cells := map[cellKey][]*zoneEntry{}
+	for _, zone := range zones {
+		m, ok := merchants[zone.MerchantID]
+		if !ok {
+			continue
+		}
+		if len(zone.Polygon) == 0 && !(m.Latitude.Valid && m.Longitude.Valid) {
+			// a radius needs a center
+			continue
+		}
+		e := &zoneEntry{zone: zone, merchant: m}
+		b := e.bounds()
+		if !fits(b) {
+			// stored before the limits existed; it would flood the index
+			logging.LoggerFromContext(ctx).Warnf("delivery zone %d of merchant %d is too large to index", zone.ID, m.ID)
+			continue
+		}
+		lo := cellOf(b.MinLat, b.MinLng, zoneIndexCell)
+		hi := cellOf(b.MaxLat, b.MaxLng, zoneIndexCell)
+		for x := lo[0]; x <= hi[0]; x++ {
+			for y := lo[1]; y <= hi[1]; y++ {
+				cells[cellKey{x, y}] = append(cells[cellKey{x, y}], e)
+			}
+		}
+	}
+
+	z.mu.Lock()
+	z.cells = cells
+	z.loadedAt = time.Now()
+	z.mu.Unlock()
+	return nil
+}
+
+// lookup returns the merchants having a zone that covers the point
+func (z *zoneIndex) lookup(lat float64, lng float64) []*models.Merchant {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	seen := map[int64]bool{}
+	results := make([]*models.Merchant, 0)
+	for _, e := range z.cells[cellOf(lat, lng, zoneIndexCell)] {
+		if seen[e.merchant.ID] || !e.contains(lat, lng) {
+			continue
+		}
+		seen[e.merchant.ID] = true
+		results = append(results, e.merchant)
+	}
+	return results
+}
+
+func (a *merchantUsecase) FetchDeliveryZones(c context.Context, id int64) ([]*models.DeliveryZone, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	m, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != models.StatusApproved {
+		return nil, models.ErrNotFound
+	}
+	return a.merchantRepo.FetchDeliveryZones(ctx, id)
+}
+
+// ReplaceDeliveryZones swaps the whole set of zones of a merchant; each zone must be a valid polygon
+// no wider than maxZoneSpan or a radius up to maxZoneRadius, and radius zones need the merchant to
+// have coordinates
+func (a *merchantUsecase) ReplaceDeliveryZones(c context.Context, id int64, zones []*models.DeliveryZone) error {
+	if err := a.authorizeMerchant(c, id); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	m, err := a.merchantRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(zones) > maxZonesPerMerchant {
+		return models.ErrBadParamInput
+	}
+	for _, zone := range zones {
+		hasPolygon := len(zone.Polygon) != 0
+		hasRadius := zone.RadiusMeters.Valid
+		if hasPolygon == hasRadius {
+			return models.ErrBadParamInput
+		}
+		if hasPolygon && (!zone.Polygon.Valid() || !fits(zone.Polygon.Bounds())) {
+			return models.ErrBadParamInput
+		}
+		if hasRadius && (zone.RadiusMeters.Float64 <= 0 || zone.RadiusMeters.Float64 > maxZoneRadius || !m.Latitude.Valid || !m.Longitude.Valid) {
+			return models.ErrBadParamInput
+		}
+	}
+
+	before, err := a.merchantRepo.FetchDeliveryZones(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.merchantRepo.ReplaceDeliveryZones(ctx, id, zones); err != nil {
+		return err
+	}
+	a.merchantsMoved()
+	a.record(ctx, models.ActionUpdate, models.EntityMerchant, id,
+		map[string]interface{}{"delivery_zones": before},
+		map[string]interface{}{"delivery_zones": zones})
+
+	return nil
+}
+
+// DeliversTo returns the delivering merchants whose zones cover the point, nearest first
+func (a *merchantUsecase) DeliversTo(c context.Context, lat float64, lng float64, categoryIDs []int64) ([]*models.Merchant, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if err := a.zones.load(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.Merchant, 0)
+	for _, m := range a.zones.lookup(lat, lng) {
+		if m.Delivery.Int64 == 1 && matchesAny(m.MbCategoryID, categoryIDs) {
+			results = append(results, m)
+		}
+	}
+	distanceTo := func(m *models.Merchant) float64 {
+		if !m.Latitude.Valid || !m.Longitude.Valid {
+			return math.Inf(1)
+		}
+		return models.Distance(lat, lng, m.Latitude.Float64, m.Longitude.Float64)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return distanceTo(results[i]) < distanceTo(results[j])
+	})
+	return results, nil
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"merchant-service/models"
+)
+
+const namespace = "merchant_service"
+
+// HTTP counts the requests served and how long they took, per route and status
+type HTTP struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewHTTP will create the HTTP collectors and register them on reg
+func NewHTTP(reg prometheus.Registerer) *HTTP {
+	m := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time spent serving HTTP requests, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// Observe records one request
+func (m *HTTP) Observe(method string, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.duration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// Repository times the calls made to a repository and counts the ones that failed, per method
+type Repository struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// NewRepository will create the repository collectors and register them on reg
+func NewRepository(reg prometheus.Registerer) *Repository {
+	m := &Repository{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Time spent in repository calls, by repository and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_errors_total",
+			Help:      "Repository calls that failed, by repository and method.",
+		}, []string{"repository", "method"}),
+	}
+	reg.MustRegister(m.duration, m.errors)
+	return m
+}
+
+// Observe records a call to method of repository that started at start. A missing row is an answer
+// rather than a failure, so ErrNotFound is not counted as an error.
+func (m *Repository) Observe(repository string, method string, start time.Time, err error) {
+	m.duration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	if err != nil && err != models.ErrNotFound {
+		m.errors.WithLabelValues(repository, method).Inc()
+	}
+}
+
+// Cache counts the lookups of a cache, from which the hit ratio of each kind of entry follows
+type Cache struct {
+	lookups *prometheus.CounterVec
+}
+
+// NewCache will create the cache collectors and register them on reg
+func NewCache(reg prometheus.Registerer) *Cache {
+	m := &Cache{
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Cache lookups, by kind of entry and result (hit or miss).",
+		}, []string{"kind", "result"}),
+	}
+	reg.MustRegister(m.lookups)
+	return m
+}
+
+// Hit records a lookup of kind answered from the cache
+func (m *Cache) Hit(kind string) {
+	m.lookups.WithLabelValues(kind, "hit").Inc()
+}
+
+// Miss records a lookup of kind that had to be loaded
+func (m *Cache) Miss(kind string) {
+	m.lookups.WithLabelValues(kind, "miss").Inc()
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo"
+
+	"merchant-service/models"
+)
+
+// clockSkew is the leeway given to the time claims of a token, for clocks slightly out of step
+const clockSkew = time.Minute
+
+// AuthConfig holds the keys used to authenticate callers, as read from the config file
+type AuthConfig struct {
+	// HS256Secret verifies tokens signed with HS256; tokens of that algorithm are refused when empty
+	HS256Secret string
+	// RS256PublicKey is the PEM public key verifying tokens signed with RS256; refused when empty
+	RS256PublicKey []byte
+	// Issuer and Audience, when set, must match the iss and aud claims of every token
+	Issuer   string
+	Audience string
+	// APIKeys maps the hex SHA-256 of each API key to the service holding it
+	APIKeys map[string]APIKey
+}
+
+// APIKey names the service holding an API key and the role it acts with
+type APIKey struct {
+	Name string `mapstructure:"name"`
+	Role string `mapstructure:"role"`
+}
+
+// Authenticator checks the credentials of a request against an AuthConfig
+type Authenticator struct {
+	hsSecret []byte
+	rsKey    *rsa.PublicKey
+	issuer   string
+	audience string
+	apiKeys  map[string]APIKey
+}
+
+// NewAuthenticator will create an Authenticator, failing when the RS256 key does not parse
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		apiKeys:  map[string]APIKey{},
+	}
+	if len(cfg.HS256Secret) != 0 {
+		a.hsSecret = []byte(cfg.HS256Secret)
+	}
+	if len(cfg.RS256PublicKey) != 0 {
+		key, err := jwt.ParseRSAPublicKeyFromPEM(cfg.RS256PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		a.rsKey = key
+	}
+	for hash, key := range cfg.APIKeys {
+		a.apiKeys[strings.ToLower(hash)] = key
+	}
+	return a, nil
+}
+
+// keyFor picks the verification key matching the algorithm the token claims, so a token cannot
+// pick an algorithm the service was not configured for
+func (a *Authenticator) keyFor(token *jwt.Token) (interface{}, error) {
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		if a.hsSecret != nil {
+			return a.hsSecret, nil
+		}
+	case jwt.SigningMethodRS256:
+		if a.rsKey != nil {
+			return a.rsKey, nil
+		}
+	}
+	return nil, errors.New("unexpected signing method")
+}
+
+// roleOrViewer falls back to the least privileged role when a credential names none, or one unknown
+func roleOrViewer(role string) string {
+	if models.ValidRole(role) {
+		return role
+	}
+	return models.RoleViewer
+}
+
+func (a *Authenticator) parseToken(raw string) (*models.Principal, error) {
+	// map claims accept aud as a string or a list, where the standard claims only take a string
+	claims := jwt.MapClaims{}
+	// the time claims are checked below, with leeway and exp required, rather than by the parser
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	if _, err := parser.ParseWithClaims(raw, claims, a.keyFor); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true) ||
+		!claims.VerifyNotBefore(now.Add(clockSkew).Unix(), false) ||
+		!claims.VerifyIssuedAt(now.Add(clockSkew).Unix(), false) {
+		return nil, models.ErrUnauthorized
+	}
+	subject, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+	if len(subject) == 0 ||
+		(len(a.issuer) != 0 && !claims.VerifyIssuer(a.issuer, true)) ||
+		(len(a.audience) != 0 && !claims.VerifyAudience(a.audience, true)) {
+		return nil, models.ErrUnauthorized
+	}
+	return &models.Principal{Subject: subject, Kind: models.PrincipalUser, Role: roleOrViewer(role)}, nil
+}
+
+func (a *Authenticator) parseAPIKey(key string) (*models.Principal, error) {
+	// keys are stored hashed, so the lookup never touches the secret itself
+	sum := sha256.Sum256([]byte(key))
+	k, ok := a.apiKeys[hex.EncodeToString(sum[:])]
+	if !ok {
+		return nil, models.ErrUnauthorized
+	}
+	return &models.Principal{Subject: k.Name, Kind: models.PrincipalService, Role: roleOrViewer(k.Role)}, nil
+}
+
+// authenticate returns the principal named by the request's credentials, nil when it carries none
+func (a *Authenticator) authenticate(req *http.Request) (*models.Principal, error) {
+	if key := req.Header.Get("X-API-Key"); len(key) != 0 {
+		return a.parseAPIKey(key)
+	}
+	header := req.Header.Get("Authorization")
+	if len(header) == 0 {
+		return nil, nil
+	}
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return nil, models.ErrUnauthorized
+	}
+	return a.parseToken(header[len(prefix):])
+}
+
+// requiresAuth reports whether the route may not be called anonymously: every write, and every
+// admin route
+func requiresAuth(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return strings.HasPrefix(c.Path(), "/merchant/admin/")
+	}
+	return true
+}
+
+// Auth will put the principal authenticated by a Bearer JWT or an X-API-Key header on the request
+// context. Invalid credentials are refused everywhere; missing ones only on writes and admin routes.
+func (m *GoMiddleware) Auth(a *Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, err := a.authenticate(c.Request())
+			if err != nil || (p == nil && requiresAuth(c)) {
+				c.Response().Header().Set("WWW-Authenticate", "Bearer")
+				return c.JSON(http.StatusUnauthorized, echo.Map{"message": models.ErrUnauthorized.Error()})
+			}
+			if p != nil {
+				req := c.Request()
+				c.SetRequest(req.WithContext(models.ContextWithPrincipal(req.Context(), p)))
+			}
+			return next(c)
+		}
+	}
+}
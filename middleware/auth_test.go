@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo"
+
+	"merchant-service/models"
+)
+
+const testSecret = "test-secret"
+
+func newTestKeys(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	raw, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "alice", "role": models.RoleOwner, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestKeyFor(t *testing.T) {
+	priv, pub := newTestKeys(t)
+
+	tests := []struct {
+		name  string
+		cfg   AuthConfig
+		token func(t *testing.T) string
+		ok    bool
+	}{
+		{
+			name:  "HS256 with the configured secret",
+			cfg:   AuthConfig{HS256Secret: testSecret},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims()) },
+			ok:    true,
+		},
+		{
+			name:  "RS256 with the configured key",
+			cfg:   AuthConfig{RS256PublicKey: pub},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, priv, validClaims()) },
+			ok:    true,
+		},
+		{
+			// the public key is no secret, so a token HMAC-signed with it must not pass as RS256
+			name:  "HS256 signed with the RS256 public key",
+			cfg:   AuthConfig{RS256PublicKey: pub},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS256, pub, validClaims()) },
+		},
+		{
+			name:  "RS256 when only HS256 is configured",
+			cfg:   AuthConfig{HS256Secret: testSecret},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, priv, validClaims()) },
+		},
+		{
+			name: "unsigned token",
+			cfg:  AuthConfig{HS256Secret: testSecret, RS256PublicKey: pub},
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+		},
+		{
+			name:  "HS384 is not accepted",
+			cfg:   AuthConfig{HS256Secret: testSecret},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS384, []byte(testSecret), validClaims()) },
+		},
+		{
+			name:  "wrong secret",
+			cfg:   AuthConfig{HS256Secret: testSecret},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodHS256, []byte("other"), validClaims()) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAuthenticator(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := a.parseToken(tt.token(t))
+			if tt.ok && (err != nil || p == nil || p.Subject != "alice") {
+				t.Fatalf("expected alice to be authenticated, got %v, %v", p, err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("expected the token to be refused, got %v", p)
+			}
+		})
+	}
+}
+
+func TestParseTokenClaims(t *testing.T) {
+	a, err := NewAuthenticator(AuthConfig{HS256Secret: testSecret, Issuer: "issuer", Audience: "merchant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		ok     bool
+		role   string
+	}{
+		{"valid", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant", "exp": now.Add(time.Hour).Unix(), "role": models.RoleEditor}, true, models.RoleEditor},
+		{"audience list", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": []string{"other", "merchant"}, "exp": now.Add(time.Hour).Unix()}, true, models.RoleViewer},
+		{"unknown role falls back to viewer", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant", "exp": now.Add(time.Hour).Unix(), "role": "root"}, true, models.RoleViewer},
+		{"missing exp", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant"}, false, ""},
+		{"expired", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant", "exp": now.Add(-time.Hour).Unix()}, false, ""},
+		{"expired within the leeway", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant", "exp": now.Add(-clockSkew / 2).Unix()}, true, models.RoleViewer},
+		{"not yet valid", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant", "exp": now.Add(2 * time.Hour).Unix(), "nbf": now.Add(time.Hour).Unix()}, false, ""},
+		{"issued in the future", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "merchant", "exp": now.Add(2 * time.Hour).Unix(), "iat": now.Add(time.Hour).Unix()}, false, ""},
+		{"missing subject", jwt.MapClaims{"iss": "issuer", "aud": "merchant", "exp": now.Add(time.Hour).Unix()}, false, ""},
+		{"wrong issuer", jwt.MapClaims{"sub": "alice", "iss": "other", "aud": "merchant", "exp": now.Add(time.Hour).Unix()}, false, ""},
+		{"wrong audience", jwt.MapClaims{"sub": "alice", "iss": "issuer", "aud": "other", "exp": now.Add(time.Hour).Unix()}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.parseToken(sign(t, jwt.SigningMethodHS256, []byte(testSecret), tt.claims))
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("expected the token to be refused, got %v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Role != tt.role || p.Kind != models.PrincipalUser {
+				t.Fatalf("expected a user with role %s, got %+v", tt.role, p)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	sum := sha256.Sum256([]byte("service-key"))
+	a, err := NewAuthenticator(AuthConfig{
+		HS256Secret: testSecret,
+		APIKeys:     map[string]APIKey{hex.EncodeToString(sum[:]): {Name: "importer", Role: models.RoleEditor}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims())
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		subject string
+		err     bool
+	}{
+		{name: "no credentials"},
+		{name: "bearer token", headers: map[string]string{"Authorization": "Bearer " + token}, subject: "alice"},
+		{name: "bearer scheme is case insensitive", headers: map[string]string{"Authorization": "bearer " + token}, subject: "alice"},
+		{name: "other scheme", headers: map[string]string{"Authorization": "Basic " + token}, err: true},
+		{name: "empty bearer", headers: map[string]string{"Authorization": "Bearer "}, err: true},
+		{name: "garbage token", headers: map[string]string{"Authorization": "Bearer not.a.token"}, err: true},
+		{name: "api key", headers: map[string]string{"X-API-Key": "service-key"}, subject: "importer"},
+		{name: "unknown api key", headers: map[string]string{"X-API-Key": "other-key"}, err: true},
+		{name: "api key wins over a token", headers: map[string]string{"X-API-Key": "service-key", "Authorization": "Bearer " + token}, subject: "importer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/merchant", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			p, err := a.authenticate(req)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.subject) == 0 {
+				if p != nil {
+					t.Fatalf("expected no principal, got %+v", p)
+				}
+				return
+			}
+			if p == nil || p.Subject != tt.subject {
+				t.Fatalf("expected %s, got %+v", tt.subject, p)
+			}
+		})
+	}
+}
+
+func TestRequiresAuth(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/merchant/:id", false},
+		{http.MethodHead, "/merchant/:id", false},
+		{http.MethodOptions, "/merchant/:id", false},
+		{http.MethodGet, "/merchant/admin/claims", true},
+		{http.MethodPost, "/merchant", true},
+		{http.MethodPut, "/merchant/:id", true},
+		{http.MethodDelete, "/merchant/:id", true},
+		{http.MethodPatch, "/merchant/:id", true},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		c := e.NewContext(httptest.NewRequest(tt.method, "/", nil), httptest.NewRecorder())
+		c.SetPath(tt.path)
+		if got := requiresAuth(c); got != tt.want {
+			t.Errorf("%s %s: requiresAuth = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
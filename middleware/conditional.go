@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/models"
+)
+
+// CacheRoute sets the Cache-Control of the successful responses of the routes whose pattern starts
+// with Path, such as "/merchant/" or "/merchant/:id/history"
+type CacheRoute struct {
+	Path         string `mapstructure:"path"`
+	CacheControl string `mapstructure:"cache_control"`
+}
+
+// HTTPCacheConfig holds the Cache-Control policies, as read from the config file
+type HTTPCacheConfig struct {
+	Default string       `mapstructure:"default"`
+	Routes  []CacheRoute `mapstructure:"routes"`
+}
+
+// cacheControlFor picks the policy of the longest route prefix matching the route pattern path. A
+// response to an authenticated caller may differ from the anonymous one, so it is never kept by a
+// shared cache.
+func (cfg *HTTPCacheConfig) cacheControlFor(path string, authenticated bool) string {
+	policy, best := cfg.Default, ""
+	for _, r := range cfg.Routes {
+		if strings.HasPrefix(path, r.Path) && len(r.Path) > len(best) {
+			policy, best = r.CacheControl, r.Path
+		}
+	}
+	if authenticated && strings.HasPrefix(policy, "public") {
+		policy = "private" + strings.TrimPrefix(policy, "public")
+	}
+	return policy
+}
+
+// bufferedWriter holds back the response so it can be answered with a 304 instead. A handler that
+// flushes is streaming, so the writer passes everything through from then on.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	streaming bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.release()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// release sends what was held back
+func (w *bufferedWriter) release() {
+	if w.status == 0 {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+}
+
+// etagMatches reports whether an If-None-Match value names tag, using the weak comparison the
+// header calls for
+func etagMatches(ifNoneMatch string, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the client's copy is still current. If-None-Match wins over
+// If-Modified-Since, which only applies to responses carrying a Last-Modified.
+func notModified(req *http.Request, header http.Header) bool {
+	if inm := req.Header.Get("If-None-Match"); len(inm) != 0 {
+		return etagMatches(inm, header.Get("ETag"))
+	}
+	ims, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ims)
+}
+
+// ConditionalGet will give every successful GET response a strong ETag, the hash of its body unless
+// the handler set one, apply the configured Cache-Control of its route, and answer 304 to a client
+// whose copy is current. Responses vary with the credentials, which caches are told.
+func (m *GoMiddleware) ConditionalGet(cfg HTTPCacheConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodGet {
+				return next(c)
+			}
+
+			res := c.Response()
+			res.Header().Add("Vary", "Authorization")
+			res.Header().Add("Vary", "X-API-Key")
+			original := res.Writer
+			w := &bufferedWriter{ResponseWriter: original}
+			res.Writer = w
+			defer func() {
+				res.Writer = original
+			}()
+
+			if err := next(c); err != nil {
+				w.release()
+				return err
+			}
+			if w.streaming {
+				return nil
+			}
+			if w.status != http.StatusOK {
+				w.release()
+				return nil
+			}
+
+			header := res.Header()
+			if len(header.Get("ETag")) == 0 {
+				sum := sha256.Sum256(w.body.Bytes())
+				header.Set("ETag", "\""+hex.EncodeToString(sum[:16])+"\"")
+			}
+			if policy := cfg.cacheControlFor(c.Path(), models.PrincipalFromContext(req.Context()) != nil); len(policy) != 0 && len(header.Get("Cache-Control")) == 0 {
+				header.Set("Cache-Control", policy)
+			}
+			if notModified(req, header) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				res.Status = http.StatusNotModified
+				res.Size = 0
+				original.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+			w.release()
+			return nil
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// CORSPolicy holds what cross-origin callers are allowed, as read from the config file
+type CORSPolicy struct {
+	// AllowOrigins lists exact origins, "*" for any, or "https://*.example.com" for subdomains
+	AllowOrigins     []string `mapstructure:"allow_origins"`
+	AllowMethods     []string `mapstructure:"allow_methods"`
+	AllowHeaders     []string `mapstructure:"allow_headers"`
+	ExposeHeaders    []string `mapstructure:"expose_headers"`
+	AllowCredentials *bool    `mapstructure:"allow_credentials"`
+	// MaxAge is how many seconds a browser may cache a preflight answer
+	MaxAge int `mapstructure:"max_age"`
+}
+
+// CORSConfig holds the default policy and the overrides for the paths starting with a prefix.
+// An override only replaces the fields it sets.
+type CORSConfig struct {
+	CORSPolicy `mapstructure:",squash"`
+	Routes     map[string]CORSPolicy `mapstructure:"routes"`
+}
+
+// merge returns p with the fields set in override replaced
+func (p CORSPolicy) merge(override CORSPolicy) CORSPolicy {
+	if len(override.AllowOrigins) != 0 {
+		p.AllowOrigins = override.AllowOrigins
+	}
+	if len(override.AllowMethods) != 0 {
+		p.AllowMethods = override.AllowMethods
+	}
+	if len(override.AllowHeaders) != 0 {
+		p.AllowHeaders = override.AllowHeaders
+	}
+	if len(override.ExposeHeaders) != 0 {
+		p.ExposeHeaders = override.ExposeHeaders
+	}
+	if override.AllowCredentials != nil {
+		p.AllowCredentials = override.AllowCredentials
+	}
+	if override.MaxAge != 0 {
+		p.MaxAge = override.MaxAge
+	}
+	return p
+}
+
+func (p *CORSPolicy) credentials() bool {
+	return p.AllowCredentials != nil && *p.AllowCredentials
+}
+
+// validate refuses a policy letting any origin make credentialed requests, which would hand every
+// site the caller's session
+func (p *CORSPolicy) validate() error {
+	if !p.credentials() {
+		return nil
+	}
+	for _, allowed := range p.AllowOrigins {
+		if allowed == "*" {
+			return errors.New(`cors: allow_origins "*" cannot be combined with allow_credentials`)
+		}
+	}
+	return nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, empty when it is not allowed
+func (p *CORSPolicy) allowOrigin(origin string) string {
+	for _, allowed := range p.AllowOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+		if i := strings.Index(allowed, "://*."); i >= 0 {
+			scheme, suffix := allowed[:i+3], allowed[i+4:]
+			lower := strings.ToLower(origin)
+			if strings.HasPrefix(lower, strings.ToLower(scheme)) && strings.HasSuffix(lower, strings.ToLower(suffix)) {
+				return origin
+			}
+		}
+	}
+	return ""
+}
+
+// policyFor picks the override with the longest prefix matching path, over the default policy
+func (cfg *CORSConfig) policyFor(path string) CORSPolicy {
+	best := ""
+	for prefix := range cfg.Routes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if len(best) == 0 {
+		return cfg.CORSPolicy
+	}
+	return cfg.CORSPolicy.merge(cfg.Routes[best])
+}
+
+// validate checks the default policy and every route as merged over it
+func (cfg *CORSConfig) validate() error {
+	if err := cfg.CORSPolicy.validate(); err != nil {
+		return err
+	}
+	for prefix, override := range cfg.Routes {
+		merged := cfg.CORSPolicy.merge(override)
+		if err := merged.validate(); err != nil {
+			return fmt.Errorf("%v (route %s)", err, prefix)
+		}
+	}
+	return nil
+}
+
+// CORS will add the CORS headers allowed by cfg and answer preflight requests itself, before any
+// authentication runs. It fails when cfg lets any origin send credentials.
+func (m *GoMiddleware) CORS(cfg CORSConfig) (echo.MiddlewareFunc, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			header := c.Response().Header()
+			header.Add("Vary", "Origin")
+			origin := req.Header.Get("Origin")
+			preflight := req.Method == http.MethodOptions && len(req.Header.Get("Access-Control-Request-Method")) != 0
+			if len(origin) == 0 {
+				return next(c)
+			}
+
+			policy := cfg.policyFor(req.URL.Path)
+			allowOrigin := policy.allowOrigin(origin)
+			if len(allowOrigin) == 0 {
+				if preflight {
+					return c.NoContent(http.StatusNoContent)
+				}
+				return next(c)
+			}
+			header.Set("Access-Control-Allow-Origin", allowOrigin)
+			if policy.credentials() {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(policy.ExposeHeaders) != 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposeHeaders, ", "))
+				}
+				return next(c)
+			}
+
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowMethods, ", "))
+			if len(policy.AllowHeaders) != 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowHeaders, ", "))
+			}
+			if policy.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+			}
+			return c.NoContent(http.StatusNoContent)
+		}
+	}, nil
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/idempotency"
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// bodyRecorder copies everything written to the response so it can be stored for replay
+type bodyRecorder struct {
+	http.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// fingerprint identifies a request by what it asks for, so a reused key with a different query or
+// body is caught
+func fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// scopedKey keeps the keys of different callers apart, so one client can neither replay nor block
+// the requests of another that picked the same key
+func scopedKey(req *http.Request, key string) string {
+	sum := sha256.Sum256([]byte(models.ActorFromContext(req.Context()) + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Idempotency will replay the stored response of a mutating request retried with the same
+// Idempotency-Key header within ttl, and reject a key reused for a different request
+func (m *GoMiddleware) Idempotency(repo idempotency.Repository, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get("Idempotency-Key")
+			switch req.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if len(key) == 0 {
+				return next(c)
+			}
+
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, echo.Map{"message": models.ErrBadParamInput.Error()})
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			ctx := req.Context()
+			key = scopedKey(req, key)
+			record := &models.IdempotencyRecord{
+				Key:         key,
+				Fingerprint: fingerprint(req, body),
+			}
+			existing, err := repo.Reserve(ctx, record, ttl)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, echo.Map{"message": models.ErrInternalServerError.Error()})
+			}
+			if existing != nil {
+				if existing.Fingerprint != record.Fingerprint {
+					return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": models.ErrIdempotencyMismatch.Error()})
+				}
+				if !existing.Completed() {
+					return c.JSON(http.StatusConflict, echo.Map{"message": models.ErrRequestInProgress.Error()})
+				}
+				c.Response().Header().Set("Idempotent-Replayed", "true")
+				return c.Blob(existing.StatusCode, existing.ContentType, existing.Body)
+			}
+
+			res := c.Response()
+			recorder := &bodyRecorder{ResponseWriter: res.Writer, body: new(bytes.Buffer)}
+			res.Writer = recorder
+			defer func() {
+				// a panicking handler never completes the record, so free the key before passing it on
+				if r := recover(); r != nil {
+					if delErr := repo.Delete(ctx, key); delErr != nil {
+						logging.LoggerFromContext(ctx).Error(delErr)
+					}
+					panic(r)
+				}
+			}()
+			err = next(c)
+			res.Writer = recorder.ResponseWriter
+			if err != nil {
+				c.Error(err)
+			}
+
+			// server errors are not final, so free the key and let the client retry
+			if res.Status >= http.StatusInternalServerError {
+				if delErr := repo.Delete(ctx, key); delErr != nil {
+					logging.LoggerFromContext(c.Request().Context()).Error(delErr)
+				}
+				return nil
+			}
+			record.StatusCode = res.Status
+			record.ContentType = res.Header().Get(echo.HeaderContentType)
+			record.Body = recorder.body.Bytes()
+			if err := repo.Complete(ctx, record); err != nil {
+				logging.LoggerFromContext(c.Request().Context()).Error(err)
+			}
+			return nil
+		}
+	}
+}
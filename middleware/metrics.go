@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/metrics"
+)
+
+// unmatchedRoute labels the requests no route answered, so unknown paths cannot grow the label set
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels the requests with a method outside knownMethods, which a client picks freely
+const otherMethod = "OTHER"
+
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return otherMethod
+}
+
+// routeAndStatus returns the route a request matched and the status it is answered with. An error is
+// written further out, so its status is worked out the way echo will.
+func routeAndStatus(c echo.Context, err error) (string, int) {
+	route := c.Path()
+	status := c.Response().Status
+	if err != nil {
+		status = http.StatusInternalServerError
+		if he, ok := err.(*echo.HTTPError); ok {
+			status = he.Code
+		}
+		if err == echo.ErrNotFound || err == echo.ErrMethodNotAllowed {
+			route = unmatchedRoute
+		}
+	}
+	return route, status
+}
+
+// Metrics will record the count and the latency of every request, per route and status
+func (m *GoMiddleware) Metrics(hm *metrics.HTTP) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route, status := routeAndStatus(c, err)
+			hm.Observe(methodLabel(c.Request().Method), route, status, time.Since(start))
+
+			return err
+		}
+	}
+}
@@ -1,20 +1,10 @@
 package middleware
 
-import "github.com/labstack/echo"
-
 // GoMiddleware represent the data-struct for middleware
 type GoMiddleware struct {
 	// another stuff , may be needed by middleware
 }
 
-// CORS will handle the CORS middleware
-func (m *GoMiddleware) CORS(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		c.Response().Header().Set("Access-Control-Allow-Origin", "*")
-		return next(c)
-	}
-}
-
 // InitMiddleware intialize the middleware
 func InitMiddleware() *GoMiddleware {
 	return &GoMiddleware{}
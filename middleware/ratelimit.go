@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"merchant-service/logging"
+	"merchant-service/models"
+	"merchant-service/ratelimit"
+)
+
+// RateLimitGroup holds the limit of the paths starting with one of Paths, as read from the config file
+type RateLimitGroup struct {
+	Paths []string `mapstructure:"paths"`
+	// Limit requests are allowed every Window seconds, with bursts of up to Burst; no limit when 0
+	Limit  int `mapstructure:"limit"`
+	Window int `mapstructure:"window"`
+	Burst  int `mapstructure:"burst"`
+}
+
+func (g *RateLimitGroup) rule() models.RateLimitRule {
+	return models.RateLimitRule{Limit: g.Limit, Window: time.Duration(g.Window) * time.Second, Burst: g.Burst}
+}
+
+// RateLimitConfig holds the default limit and the named groups overriding it
+type RateLimitConfig struct {
+	RateLimitGroup `mapstructure:",squash"`
+	Groups         map[string]RateLimitGroup `mapstructure:"groups"`
+	// TrustedProxies lists the CIDRs of the proxies in front of the service; X-Forwarded-For is only
+	// read from them, anyone else could put any address in it
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// trustedNets parses TrustedProxies, a bare address standing for itself
+func (cfg *RateLimitConfig) trustedNets() ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cfg.TrustedProxies))
+	for _, s := range cfg.TrustedProxies {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address the request came from. Behind trusted proxies it is the last address
+// of X-Forwarded-For not belonging to one of them, the first hop nobody trusted could forge.
+func clientIP(req *http.Request, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !contains(trusted, ip) {
+		return host
+	}
+	hops := strings.Split(req.Header.Get(echo.HeaderXForwardedFor), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		if !contains(trusted, hop) {
+			return hop.String()
+		}
+	}
+	return host
+}
+
+// groupFor picks the group with the longest path prefix matching path, the default one otherwise
+func (cfg *RateLimitConfig) groupFor(path string) (string, RateLimitGroup) {
+	name, group, best := "default", cfg.RateLimitGroup, ""
+	for n, g := range cfg.Groups {
+		for _, prefix := range g.Paths {
+			if strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+				name, group, best = n, g, prefix
+			}
+		}
+	}
+	return name, group
+}
+
+// clientKey identifies the caller: the service of an API key, the subject of a token, or else the
+// client IP
+func clientKey(req *http.Request, trusted []*net.IPNet) string {
+	if p := models.PrincipalFromContext(req.Context()); p != nil {
+		if p.Kind == models.PrincipalService {
+			return "key:" + p.Subject
+		}
+		return "user:" + p.Subject
+	}
+	return "ip:" + clientIP(req, trusted)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// RateLimit will refuse with 429 the requests of a caller that used up the limit of the route
+// group, and report the state of its bucket in RateLimit-* headers. It runs after Auth so
+// authenticated callers are told apart. It fails when a trusted proxy does not parse.
+func (m *GoMiddleware) RateLimit(limiter ratelimit.Limiter, cfg RateLimitConfig) (echo.MiddlewareFunc, error) {
+	trusted, err := cfg.trustedNets()
+	if err != nil {
+		return nil, err
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			name, group := cfg.groupFor(c.Request().URL.Path)
+			if group.Limit <= 0 || group.Window <= 0 {
+				return next(c)
+			}
+
+			res, err := limiter.Take(c.Request().Context(), name+"|"+clientKey(c.Request(), trusted), group.rule())
+			if err != nil {
+				// a broken limiter should not take the service down with it
+				logging.LoggerFromContext(c.Request().Context()).Error(err)
+				return next(c)
+			}
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", seconds(res.Reset))
+			if !res.Allowed {
+				header.Set("Retry-After", seconds(res.RetryAfter))
+				return c.JSON(http.StatusTooManyRequests, echo.Map{"message": models.ErrTooManyRequests.Error()})
+			}
+			return next(c)
+		}
+	}, nil
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"merchant-service/logging"
+	"merchant-service/models"
+)
+
+// maxRequestIDLength bounds a request ID taken from the caller, so it cannot flood the logs
+const maxRequestIDLength = 128
+
+// validRequestID accepts the printable ASCII IDs a caller may propagate
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestLog will give each request an ID, the caller's X-Request-ID when it sent a valid one, put a
+// log entry carrying it, and the trace ID when the request is traced, on the request context, and write an access log line once the response is out
+func (m *GoMiddleware) RequestLog(logger *logrus.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			res := c.Response()
+
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			res.Header().Set(echo.HeaderXRequestID, id)
+			entry := logger.WithField("request_id", id)
+			if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+				entry = entry.WithField("trace_id", sc.TraceID().String())
+			}
+			c.SetRequest(req.WithContext(logging.ContextWithLogger(req.Context(), entry)))
+
+			err := next(c)
+			if err != nil {
+				// let echo write the error now, so the status and size below are the ones sent
+				c.Error(err)
+			}
+
+			req = c.Request()
+			fields := logrus.Fields{
+				"method":     req.Method,
+				"path":       req.URL.Path,
+				"route":      c.Path(),
+				"status":     res.Status,
+				"latency_ms": float64(time.Since(start)) / float64(time.Millisecond),
+				"bytes_in":   req.ContentLength,
+				"bytes_out":  res.Size,
+				"remote_ip":  c.RealIP(),
+				"actor":      models.ActorFromContext(req.Context()),
+			}
+			if err != nil {
+				fields["error"] = err.Error()
+			}
+			entry.WithFields(fields).Info("request")
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,22 @@
+-- Replaces the is_deleted flag of mb_merchant with deleted_at and adds the created_at and
+-- updated_at timestamps the changes feed pages on. Run it once, before deploying the service
+-- version reading these columns.
+
+ALTER TABLE mb_merchant
+  ADD COLUMN created_at DATETIME NULL,
+  ADD COLUMN updated_at DATETIME NULL,
+  ADD COLUMN deleted_at DATETIME NULL;
+
+-- is_deleted was set on removed merchants and left NULL on live ones
+UPDATE mb_merchant SET deleted_at = NOW() WHERE is_deleted IS NOT NULL AND deleted_at IS NULL;
+
+-- the existing catalog has no history; give it a timestamp so the feed hands it out once
+UPDATE mb_merchant SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE mb_merchant SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE mb_merchant
+  MODIFY created_at DATETIME NOT NULL,
+  MODIFY updated_at DATETIME NOT NULL;
+
+-- the changes feed reads in (updated_at, mb_merchant_id) order
+CREATE INDEX idx_mb_merchant_updated ON mb_merchant (updated_at, mb_merchant_id);
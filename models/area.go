@@ -11,4 +11,12 @@ type Area struct {
 	Name        null.String `json:"name"`
 	Description null.String `json:"description"`
 	Image       null.String `json:"image"`
+	Boundary    Polygon     `json:"boundary,omitempty"`
+}
+
+// AreaMismatch represent a merchant located outside the boundary of its area, with the area that
+// holds its coordinates when there is one
+type AreaMismatch struct {
+	Merchant      *Merchant `json:"merchant"`
+	SuggestedArea *Area     `json:"suggested_area"`
 }
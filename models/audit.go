@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Audited entity types
+const (
+	EntityMerchant = "merchant"
+	EntityCategory = "category"
+	EntityArea     = "area"
+	EntityClaim    = "claim"
+)
+
+// Audited actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionStatus = "status"
+	ActionRevert = "revert"
+	ActionMerge  = "merge"
+)
+
+// FieldChange represent the before and after value of one changed field
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry represent one recorded write to an audited entity
+type AuditEntry struct {
+	ID         int64          `json:"id"`
+	Actor      string         `json:"actor"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entity_type"`
+	EntityID   int64          `json:"entity_id"`
+	Changes    []*FieldChange `json:"changes"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// AuditFilter represent the criteria of an audit log query, zero values match everything
+type AuditFilter struct {
+	Actor      string
+	EntityType string
+	EntityID   int64
+	From       time.Time
+	To         time.Time
+	Page       int
+	Offset     int
+}
@@ -0,0 +1,32 @@
+package models
+
+import "gopkg.in/guregu/null.v3"
+
+// DeliveryPricing represent the delivery pricing rules of a merchant
+type DeliveryPricing struct {
+	MerchantID       int64      `json:"mb_merchant_id"`
+	BaseFee          float64    `json:"base_fee"`
+	PerKmFee         float64    `json:"per_km_fee"`
+	FreeOverSubtotal null.Float `json:"free_over_subtotal"`
+	MinOrder         null.Float `json:"min_order"`
+	PrepMinutes      int        `json:"prep_minutes"`
+}
+
+// Reasons a delivery quote is not eligible
+const (
+	QuoteNoDelivery   = "merchant does not deliver"
+	QuoteNoLocation   = "merchant has no location"
+	QuoteNoPricing    = "merchant has no delivery pricing"
+	QuoteOutsideZones = "address is outside the delivery zones"
+	QuoteBelowMinimum = "subtotal is below the minimum order"
+)
+
+// DeliveryQuote represent the estimated fee and time of delivering an order to one address
+type DeliveryQuote struct {
+	MerchantID     int64   `json:"mb_merchant_id"`
+	Eligible       bool    `json:"eligible"`
+	Reason         string  `json:"reason,omitempty"`
+	DistanceMeters float64 `json:"distance_meters"`
+	Fee            float64 `json:"fee"`
+	EtaMinutes     int     `json:"eta_minutes"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// Duplicate candidate review states
+const (
+	DuplicatePending   = "pending"
+	DuplicateMerged    = "merged"
+	DuplicateDismissed = "dismissed"
+)
+
+// DuplicateCandidate represent a pair of merchants that look like the same shop
+type DuplicateCandidate struct {
+	ID             int64      `json:"id"`
+	MerchantID     int64      `json:"mb_merchant_id"`
+	DuplicateID    int64      `json:"duplicate_id"`
+	Score          float64    `json:"score"`
+	NameScore      float64    `json:"name_score"`
+	PhoneMatch     bool       `json:"phone_match"`
+	DistanceMeters null.Float `json:"distance_meters"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -11,4 +11,20 @@ var (
 	ErrConflict = errors.New("Your Item already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
 	ErrBadParamInput = errors.New("Given Param is not valid")
+	// ErrPreconditionRequired will throw if a conditional write is sent without the version it expects
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+	// ErrIdempotencyMismatch will throw if an idempotency key is reused for a different request
+	ErrIdempotencyMismatch = errors.New("Idempotency key was already used for a different request")
+	// ErrRequestInProgress will throw if a request with the same idempotency key has not finished yet
+	ErrRequestInProgress = errors.New("A request with this idempotency key is still in progress")
+	// ErrUnauthorized will throw if the request carries no credentials, or credentials that are not valid
+	ErrUnauthorized = errors.New("Authentication is required")
+	// ErrForbidden will throw if the authenticated caller is not allowed the requested action
+	ErrForbidden = errors.New("You are not allowed to perform this action")
+	// ErrTooManyRequests will throw if the caller used up its rate limit
+	ErrTooManyRequests = errors.New("Too many requests, retry later")
+	// ErrAlreadyOwned will throw if a claim needing an unowned merchant finds it owned
+	ErrAlreadyOwned = errors.New("Merchant already has an owner")
+	// ErrInvalidTransition will throw if the requested status change is not allowed from the current status
+	ErrInvalidTransition = errors.New("Status transition is not allowed")
 )
@@ -0,0 +1,8 @@
+package models
+
+// MerchantFilter represent the conditions of a merchant filter request. Fields maps a filterable
+// column to the values it may take; the repository only accepts the columns it whitelists.
+type MerchantFilter struct {
+	Keyword string
+	Fields  map[string][]string
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"math"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000.0
+
+// Distance returns the great-circle distance in meters between two coordinates
+func Distance(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64 {
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// BoundingBox represent a rectangle of coordinates, as sent in a bbox=minLng,minLat,maxLng,maxLat param
+type BoundingBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// Contains reports whether the point lies inside the box, edges included
+func (b *BoundingBox) Contains(lat float64, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// MerchantCluster represent a group of nearby merchants on the map, or a single merchant when Count is 1
+type MerchantCluster struct {
+	Count         int       `json:"count"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	TopCategoryID null.Int  `json:"top_category_id"`
+	Merchant      *Merchant `json:"merchant,omitempty"`
+}
+
+// MaxPolygonPoints bounds the size of a ring, which every containment test walks whole
+const MaxPolygonPoints = 1000
+
+// Polygon represent a closed ring of [longitude, latitude] points, in GeoJSON order
+type Polygon [][2]float64
+
+// Valid reports whether the ring has between 3 and MaxPolygonPoints points and every point is a
+// real coordinate
+func (p Polygon) Valid() bool {
+	if len(p) < 3 || len(p) > MaxPolygonPoints {
+		return false
+	}
+	for _, pt := range p {
+		if pt[0] < -180 || pt[0] > 180 || pt[1] < -90 || pt[1] > 90 {
+			return false
+		}
+	}
+	return true
+}
+
+// Bounds returns the smallest box holding the ring
+func (p Polygon) Bounds() BoundingBox {
+	b := BoundingBox{MinLng: p[0][0], MaxLng: p[0][0], MinLat: p[0][1], MaxLat: p[0][1]}
+	for _, pt := range p[1:] {
+		if pt[0] < b.MinLng {
+			b.MinLng = pt[0]
+		}
+		if pt[0] > b.MaxLng {
+			b.MaxLng = pt[0]
+		}
+		if pt[1] < b.MinLat {
+			b.MinLat = pt[1]
+		}
+		if pt[1] > b.MaxLat {
+			b.MaxLat = pt[1]
+		}
+	}
+	return b
+}
+
+// Contains reports whether the point lies inside the ring, using the even-odd rule
+func (p Polygon) Contains(lat float64, lng float64) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		xi, yi := p[i][0], p[i][1]
+		xj, yj := p[j][0], p[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DeliveryZone represent an area a merchant delivers to, either a polygon or a radius around the merchant
+type DeliveryZone struct {
+	ID           int64       `json:"id"`
+	MerchantID   int64       `json:"mb_merchant_id"`
+	Name         null.String `json:"name"`
+	Polygon      Polygon     `json:"polygon,omitempty"`
+	RadiusMeters null.Float  `json:"radius_meters"`
+}
+
+// GeocodeResult represent an address matched to a coordinate by a geocoder
+type GeocodeResult struct {
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
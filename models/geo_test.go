@@ -0,0 +1,36 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name       string
+		lat1, lng1 float64
+		lat2, lng2 float64
+		want       float64
+		tolerance  float64
+	}{
+		{"same point", 21.0285, 105.8542, 21.0285, 105.8542, 0, 1e-9},
+		{"one degree of latitude", 0, 0, 1, 0, 111195, 1},
+		{"one degree of longitude on the equator", 0, 0, 0, 1, 111195, 1},
+		{"one degree of longitude at 60 degrees", 60, 0, 60, 1, 55597, 1},
+		{"Hanoi to Ho Chi Minh City", 21.0285, 105.8542, 10.8231, 106.6297, 1138000, 2000},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111195, 1},
+		{"antipodes", 0, 0, 0, 180, math.Pi * earthRadius, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Fatalf("Distance = %.1f, want %.1f ± %.1f", got, tt.want, tt.tolerance)
+			}
+			if back := Distance(tt.lat2, tt.lng2, tt.lat1, tt.lng1); math.Abs(back-got) > 1e-6 {
+				t.Fatalf("Distance is not symmetric: %.6f and %.6f", got, back)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyRecord represent a stored mutating request and the response it produced
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// Completed reports whether the original request has finished and its response can be replayed
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
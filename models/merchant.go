@@ -10,6 +10,7 @@ type Merchant struct {
 	MbCategoryID null.Int    `json:"mb_category_id"`
 	AreaID       null.Int    `json:"area_id"`
 	Name         null.String `json:"name"`
+	Slug         null.String `json:"slug"`
 	Address      null.String `json:"address"`
 	Latitude     null.Float  `json:"latitude"`
 	Longitude    null.Float  `json:"longitude"`
@@ -20,5 +21,10 @@ type Merchant struct {
 	TimeStart    null.String `json:"time_start"`
 	TimeEnd      null.String `json:"time_end"`
 	Facebook     null.String `json:"facebook"`
+	Status       string      `json:"status"`
+	Version      int64       `json:"version"`
+	CreatedAt    null.Time   `json:"created_at"`
+	UpdatedAt    null.Time   `json:"updated_at"`
+	DeletedAt    null.Time   `json:"deleted_at"`
 	Images       []*Image    `json:"images"`
 }
@@ -0,0 +1,9 @@
+package models
+
+// MerchantChanges represent one page of the incremental merchant sync feed
+type MerchantChanges struct {
+	Upserted   []*Merchant `json:"upserted"`
+	Deleted    []int64     `json:"deleted"`
+	NextCursor string      `json:"next_cursor"`
+	HasMore    bool        `json:"has_more"`
+}
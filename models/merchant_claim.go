@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// Merchant claim states. A claim waits for its verification code, then makes the claimant an owner,
+// unless the merchant is already owned or the claim is disputed, which leaves it to an admin.
+const (
+	ClaimPending  = "pending"
+	ClaimApproved = "approved"
+	ClaimDisputed = "disputed"
+	ClaimRejected = "rejected"
+)
+
+// MerchantClaim represent a request by a principal to become an owner of a merchant
+type MerchantClaim struct {
+	ID         int64       `json:"id"`
+	MerchantID int64       `json:"mb_merchant_id"`
+	Subject    string      `json:"subject"`
+	Status     string      `json:"status"`
+	CodeHash   string      `json:"-"`
+	Attempts   int         `json:"-"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	Reason     null.String `json:"reason"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
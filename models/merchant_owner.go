@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// MerchantOwner represent a principal allowed to edit a merchant it owns
+type MerchantOwner struct {
+	MerchantID int64     `json:"mb_merchant_id"`
+	Subject    string    `json:"subject"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// MerchantRevision represent a full snapshot of a merchant, images included, taken after one write
+type MerchantRevision struct {
+	ID         int64     `json:"id"`
+	MerchantID int64     `json:"mb_merchant_id"`
+	Revision   int64     `json:"revision"`
+	Snapshot   *Merchant `json:"snapshot"`
+	Actor      string    `json:"actor"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// Merchant moderation states
+const (
+	StatusDraft     = "draft"
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusSuspended = "suspended"
+)
+
+// MerchantStatusTransition represent one recorded change of a merchant's moderation status
+type MerchantStatusTransition struct {
+	ID         int64       `json:"id"`
+	MerchantID int64       `json:"mb_merchant_id"`
+	FromStatus string      `json:"from_status"`
+	ToStatus   string      `json:"to_status"`
+	Reason     null.String `json:"reason"`
+	Actor      string      `json:"actor"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
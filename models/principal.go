@@ -0,0 +1,65 @@
+package models
+
+import "context"
+
+// AnonymousActor is reported when the request carries no principal
+const AnonymousActor = "anonymous"
+
+// Kinds of principal
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
+
+// Roles a principal may hold, each allowed what the ones before it are
+const (
+	// RoleViewer may only read
+	RoleViewer = "viewer"
+	// RoleOwner may also create merchants and edit the ones it owns
+	RoleOwner = "owner"
+	// RoleEditor may also edit every merchant, category and area
+	RoleEditor = "editor"
+	// RoleAdmin may also moderate, merge and manage ownership
+	RoleAdmin = "admin"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleOwner: 2, RoleEditor: 3, RoleAdmin: 4}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Principal represent the authenticated caller of a request, a user from a JWT or a service from an API key
+type Principal struct {
+	Subject string `json:"subject"`
+	Kind    string `json:"kind"`
+	Role    string `json:"role"`
+}
+
+// HasRole reports whether the principal holds role or one above it
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && roleRanks[p.Role] >= roleRanks[role]
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the authenticated caller
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored on ctx, or nil for an anonymous request
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// ActorFromContext names the caller of ctx for the audit trail, or AnonymousActor
+func ActorFromContext(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil && len(p.Subject) != 0 {
+		return p.Kind + ":" + p.Subject
+	}
+	return AnonymousActor
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RateLimitRule represent a token bucket refilled with Limit tokens every Window and holding at most Burst
+type RateLimitRule struct {
+	Limit  int
+	Window time.Duration
+	Burst  int
+}
+
+// RateLimitResult represent the state of a bucket after a request took a token from it
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until a refused request would be allowed
+	RetryAfter time.Duration
+}
@@ -0,0 +1,8 @@
+package notifier
+
+import "context"
+
+// Notifier represent the contract of a provider delivering text messages to a phone number
+type Notifier interface {
+	Send(ctx context.Context, phone string, message string) error
+}
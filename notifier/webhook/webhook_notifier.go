@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"merchant-service/logging"
+	"merchant-service/notifier"
+)
+
+type webhookNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// message is the body posted to the SMS gateway
+type message struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// NewWebhookNotifier will create a notifier posting each message to the SMS gateway at url,
+// authenticated with a bearer token when one is given
+func NewWebhookNotifier(url string, token string, timeout time.Duration) notifier.Notifier {
+	return &webhookNotifier{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *webhookNotifier) Send(ctx context.Context, phone string, body string) error {
+	payload, err := json.Marshal(&message{To: phone, Body: body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req.WithContext(ctx))
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error(err)
+		return err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error(err)
+		}
+	}()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sms gateway: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"merchant-service/models"
+	"merchant-service/ratelimit"
+)
+
+// sweepInterval is how often buckets left full, which hold no information, are dropped
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   models.RateLimitRule
+}
+
+// refill adds the tokens earned since the last request and returns the refill rate in tokens per second
+func (b *bucket) refill(now time.Time) float64 {
+	rate := float64(b.rule.Limit) / b.rule.Window.Seconds()
+	b.tokens = math.Min(float64(b.rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	return rate
+}
+
+type memoryLimiter struct {
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	maxBuckets int
+	lastSweep  time.Time
+}
+
+// NewMemoryLimiter will create a token bucket limiter keeping up to maxBuckets buckets in memory
+func NewMemoryLimiter(maxBuckets int) ratelimit.Limiter {
+	return &memoryLimiter{buckets: map[string]*bucket{}, maxBuckets: maxBuckets, lastSweep: time.Now()}
+}
+
+// sweep drops the buckets that refilled completely; a new bucket starts full, so nothing is lost
+func (l *memoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *memoryLimiter) Take(ctx context.Context, key string, rule models.RateLimitRule) (*models.RateLimitResult, error) {
+	if rule.Burst <= 0 {
+		rule.Burst = rule.Limit
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok && len(l.buckets) >= l.maxBuckets {
+		l.sweep(now)
+	}
+	if !ok && len(l.buckets) >= l.maxBuckets {
+		// dropping a bucket that still holds state would hand its caller a fresh burst, so new
+		// callers wait until the busy ones refill instead
+		return &models.RateLimitResult{Limit: rule.Burst, RetryAfter: sweepInterval, Reset: sweepInterval}, nil
+	}
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.rule = rule
+	rate := b.refill(now)
+
+	res := &models.RateLimitResult{Limit: rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((float64(rule.Burst) - b.tokens) / rate * float64(time.Second))
+
+	return res, nil
+}
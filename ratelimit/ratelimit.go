@@ -0,0 +1,14 @@
+package ratelimit
+
+import (
+	"context"
+
+	models "merchant-service/models"
+)
+
+// Limiter represent the rate limiter's contract. The in-memory limiter only counts the requests of
+// one instance; a shared store can replace it when the service runs on several.
+type Limiter interface {
+	// Take removes a token from the bucket of key, refusing the request when the bucket is empty
+	Take(ctx context.Context, key string, rule models.RateLimitRule) (*models.RateLimitResult, error)
+}
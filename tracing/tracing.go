@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Exporters a Config may name
+const (
+	// ExporterNone keeps tracing off; spans are still propagated but never recorded
+	ExporterNone = "none"
+	// ExporterStdout writes finished spans to the standard output, one JSON document each
+	ExporterStdout = "stdout"
+	// ExporterFile appends finished spans to Config.File
+	ExporterFile = "file"
+)
+
+// Config holds where spans go and how many of them, as read from the config file
+type Config struct {
+	Exporter string `mapstructure:"exporter"`
+	File     string `mapstructure:"file"`
+	// SampleRatio is the share of the traces started here that are recorded; a trace started by a
+	// caller follows the caller's decision
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// Init will install the tracer provider described by cfg and the W3C trace context propagator as
+// the global ones. The returned function flushes the spans still buffered and must be called
+// before the service exits.
+func Init(service string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var w io.Writer
+	var file *os.File
+	switch cfg.Exporter {
+	case ExporterStdout:
+		w = os.Stdout
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w, file = f, f
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}